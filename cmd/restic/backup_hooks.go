@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/restic/restic/internal/archiver"
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/backend/location"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui/backup"
+)
+
+// backupHooks runs the commands configured via --pre-command, --post-command
+// and --on-failure-command.
+type backupHooks struct {
+	pre, post, onFailure string
+
+	repository string
+	printer    backup.ProgressPrinter
+	stdout     io.Writer
+	stderr     io.Writer
+
+	// snapshotID and summary are passed to the post and on-failure commands
+	snapshotID restic.ID
+	summary    *archiver.Summary
+}
+
+func newBackupHooks(opts BackupOptions, gopts GlobalOptions, printer backup.ProgressPrinter) *backupHooks {
+	// an invalid repository location is reported when opening the repository
+	repo, _ := ReadRepo(gopts)
+
+	h := &backupHooks{
+		pre:        opts.PreCommand,
+		post:       opts.PostCommand,
+		onFailure:  opts.OnFailureCommand,
+		repository: location.StripPassword(gopts.backends, repo),
+		printer:    printer,
+		stdout:     globalOptions.stdout,
+		stderr:     globalOptions.stderr,
+	}
+	if gopts.JSON {
+		// keep stdout reserved for the JSON messages
+		h.stdout = globalOptions.stderr
+	}
+	return h
+}
+
+// checkHookCommands returns an error if one of the commands cannot be parsed.
+func checkHookCommands(opts BackupOptions) error {
+	for _, hook := range []struct {
+		flag, command string
+	}{
+		{"--pre-command", opts.PreCommand},
+		{"--post-command", opts.PostCommand},
+		{"--on-failure-command", opts.OnFailureCommand},
+	} {
+		if hook.command == "" {
+			continue
+		}
+		if _, err := backend.SplitShellStrings(hook.command); err != nil {
+			return errors.Fatalf("invalid %v: %v", hook.flag, err)
+		}
+	}
+	return nil
+}
+
+// runPre runs the pre-command. The backup must be aborted if an error is
+// returned.
+func (h *backupHooks) runPre(ctx context.Context) error {
+	return h.run(ctx, "pre", h.pre, h.env(0, nil))
+}
+
+// runPost runs the post-command after the snapshot has been saved. As the
+// backup itself has succeeded, an error of the command is only printed.
+func (h *backupHooks) runPost(ctx context.Context, exitStatus int) {
+	if err := h.run(ctx, "post", h.post, h.env(exitStatus, nil)); err != nil {
+		Warnf("%v\n", err)
+	}
+}
+
+// runOnFailure runs the on-failure command for the error the backup failed
+// with. As the backup has already failed, an error of the command itself is
+// only printed.
+func (h *backupHooks) runOnFailure(err error) {
+	exitStatus := 1
+	if err == ErrInvalidSourceData {
+		exitStatus = 3
+	}
+
+	// the context of the backup may already be cancelled
+	herr := h.run(context.Background(), "on-failure", h.onFailure, h.env(exitStatus, err))
+	if herr != nil {
+		Warnf("%v\n", herr)
+	}
+}
+
+func (h *backupHooks) run(ctx context.Context, hook, command string, env []string) error {
+	if command == "" {
+		return nil
+	}
+
+	args, err := backend.SplitShellStrings(command)
+	if err != nil {
+		return errors.Fatalf("invalid --%v-command: %v", hook, err)
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = append(cmd.Env, "RESTIC_BACKUP_HOOK="+hook)
+	cmd.Stdout = h.stdout
+	cmd.Stderr = h.stderr
+
+	start := time.Now()
+	err = cmd.Run()
+	exitCode := 0
+	if err != nil {
+		// the command could not be started at all
+		exitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+	h.printer.ReportHook(hook, command, exitCode, time.Since(start))
+
+	if err != nil {
+		return errors.Fatalf("%v command failed: %v", hook, err)
+	}
+	return nil
+}
+
+// env returns the environment variables which describe the state of the
+// backup for the hook commands.
+func (h *backupHooks) env(exitStatus int, err error) []string {
+	env := []string{
+		"RESTIC_BACKUP_REPOSITORY=" + h.repository,
+		fmt.Sprintf("RESTIC_BACKUP_EXIT_STATUS=%d", exitStatus),
+	}
+	if err != nil {
+		env = append(env, "RESTIC_BACKUP_ERROR="+err.Error())
+	}
+	if !h.snapshotID.IsNull() {
+		env = append(env, "RESTIC_BACKUP_SNAPSHOT_ID="+h.snapshotID.String())
+	}
+
+	if s := h.summary; s != nil {
+		for _, v := range []struct {
+			name  string
+			value interface{}
+		}{
+			{"FILES_NEW", s.Files.New},
+			{"FILES_CHANGED", s.Files.Changed},
+			{"FILES_UNMODIFIED", s.Files.Unchanged},
+			{"DIRS_NEW", s.Dirs.New},
+			{"DIRS_CHANGED", s.Dirs.Changed},
+			{"DIRS_UNMODIFIED", s.Dirs.Unchanged},
+			{"DATA_ADDED", s.ItemStats.DataSize + s.ItemStats.TreeSize},
+			{"DATA_ADDED_PACKED", s.ItemStats.DataSizeInRepo + s.ItemStats.TreeSizeInRepo},
+			{"TOTAL_FILES_PROCESSED", s.Files.New + s.Files.Changed + s.Files.Unchanged},
+			{"TOTAL_BYTES_PROCESSED", s.ProcessedBytes},
+			{"TOTAL_DURATION", fmt.Sprintf("%.3f", s.BackupEnd.Sub(s.BackupStart).Seconds())},
+		} {
+			env = append(env, fmt.Sprintf("RESTIC_BACKUP_%v=%v", v.name, v.value))
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+)
+
+var cmdAnnotate = &cobra.Command{
+	Use:   "annotate [flags] [snapshotID ...]",
+	Short: "Modify the description of snapshots",
+	Long: `
+The "annotate" command sets or removes the free-text description of existing
+snapshots. The description is shown by the "snapshots" command.
+
+When no snapshotID is given, all snapshots matching the host, tag, path and label filter criteria are modified.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAnnotate(cmd.Context(), annotateOptions, globalOptions, args)
+	},
+}
+
+// AnnotateOptions bundles all options for the 'annotate' command.
+type AnnotateOptions struct {
+	restic.SnapshotFilter
+	Description     string
+	DescriptionFile string
+	Clear           bool
+}
+
+var annotateOptions AnnotateOptions
+
+func init() {
+	cmdRoot.AddCommand(cmdAnnotate)
+
+	f := cmdAnnotate.Flags()
+	f.StringVar(&annotateOptions.Description, "description", "", "set the description of the snapshots to `text`")
+	f.StringVar(&annotateOptions.DescriptionFile, "description-file", "", "read the description of the snapshots from `file`")
+	f.BoolVar(&annotateOptions.Clear, "clear", false, "remove the description from the snapshots")
+	initMultiSnapshotFilter(f, &annotateOptions.SnapshotFilter, true)
+}
+
+// readDescription returns the description given either directly as text or
+// in a file. Leading and trailing whitespace is removed.
+func readDescription(text, filename string) (string, error) {
+	if text != "" && filename != "" {
+		return "", errors.Fatal("--description and --description-file cannot be given at the same time")
+	}
+
+	if filename != "" {
+		buf, err := os.ReadFile(filename)
+		if err != nil {
+			return "", errors.Fatalf("unable to read description file: %v", err)
+		}
+		text = string(buf)
+	}
+
+	return strings.TrimSpace(text), nil
+}
+
+func runAnnotate(ctx context.Context, opts AnnotateOptions, gopts GlobalOptions, args []string) error {
+	description, err := readDescription(opts.Description, opts.DescriptionFile)
+	if err != nil {
+		return err
+	}
+	if description == "" && !opts.Clear {
+		return errors.Fatal("nothing to do!")
+	}
+	if description != "" && opts.Clear {
+		return errors.Fatal("--clear and --description/--description-file cannot be given at the same time")
+	}
+
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	if !gopts.NoLock {
+		Verbosef("create exclusive lock for repository\n")
+		var lock *restic.Lock
+		lock, ctx, err = lockRepoExclusive(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	changeCnt := 0
+	for sn := range FindFilteredSnapshots(ctx, repo, repo, &opts.SnapshotFilter, args) {
+		if sn.Description == description {
+			continue
+		}
+
+		sn.Description = description
+		if err := replaceSnapshot(ctx, repo, sn); err != nil {
+			Warnf("unable to modify the description for snapshot ID %q, ignoring: %v\n", sn.ID(), err)
+			continue
+		}
+		changeCnt++
+	}
+	if changeCnt == 0 {
+		Verbosef("no snapshots were modified\n")
+	} else {
+		Verbosef("modified the description of %v snapshots\n", changeCnt)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testRunAnnotate(t testing.TB, opts AnnotateOptions, gopts GlobalOptions) {
+	rtest.OK(t, runAnnotate(context.TODO(), opts, gopts, []string{}))
+}
+
+func TestAnnotate(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{Description: "pre-upgrade of PostgreSQL 15→16\n"}, env.gopts)
+	testRunCheck(t, env.gopts)
+	newest, _ := testRunSnapshots(t, env.gopts)
+	if newest == nil {
+		t.Fatal("expected a new backup, got nil")
+	}
+	rtest.Equals(t, "pre-upgrade of PostgreSQL 15→16", newest.Description)
+	originalID := *newest.ID
+
+	buf := &bytes.Buffer{}
+	PrintSnapshots(buf, restic.Snapshots{newest.Snapshot}, nil, false)
+	rtest.Assert(t, strings.Contains(buf.String(), "pre-upgrade of PostgreSQL 15→16"),
+		"description missing from snapshots output: %v", buf.String())
+
+	testRunAnnotate(t, AnnotateOptions{Description: "first line\nsecond line"}, env.gopts)
+	testRunCheck(t, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	if newest == nil {
+		t.Fatal("expected a backup, got nil")
+	}
+	rtest.Equals(t, "first line\nsecond line", newest.Description)
+	rtest.Assert(t, newest.Original != nil && *newest.Original == originalID,
+		"expected original ID to be set to the first snapshot id")
+
+	testRunAnnotate(t, AnnotateOptions{Clear: true}, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	rtest.Equals(t, "", newest.Description)
+	rtest.Assert(t, newest.Original != nil && *newest.Original == originalID,
+		"expected original ID to be retained")
+
+	err := runAnnotate(context.TODO(), AnnotateOptions{}, env.gopts, nil)
+	rtest.Assert(t, err != nil, "missing error for annotate without description")
+}
@@ -18,6 +18,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/restic/restic/internal/archiver"
+	"github.com/restic/restic/internal/backend"
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/fs"
@@ -63,30 +64,56 @@ Exit status is 3 if some source data could not be read (incomplete snapshot crea
 // BackupOptions bundles all options for the backup command.
 type BackupOptions struct {
 	excludePatternOptions
-
-	Parent            string
-	GroupBy           restic.SnapshotGroupByOptions
-	Force             bool
-	ExcludeOtherFS    bool
-	ExcludeIfPresent  []string
-	ExcludeCaches     bool
-	ExcludeLargerThan string
-	Stdin             bool
-	StdinFilename     string
-	StdinCommand      bool
-	Tags              restic.TagLists
-	Host              string
-	FilesFrom         []string
-	FilesFromVerbatim []string
-	FilesFromRaw      []string
-	TimeStamp         string
-	WithAtime         bool
-	IgnoreInode       bool
-	IgnoreCtime       bool
-	UseFsSnapshot     bool
-	DryRun            bool
-	ReadConcurrency   uint
-	NoScan            bool
+	xattrFilterOptions
+
+	Parent             string
+	GroupBy            restic.SnapshotGroupByOptions
+	Force              bool
+	ExcludeOtherFS     bool
+	ExcludeFSTypes     []string
+	ExcludeIfPresent   []string
+	ExcludeCaches      bool
+	IgnoreFileNames    []string
+	ExcludeLargerThan  string
+	ExcludeOlderThan   restic.Duration
+	ExcludeNewerThan   restic.Duration
+	ExcludeOwners      []string
+	ExcludeTypes       []string
+	ExcludeNoDump      bool
+	Stdin              bool
+	StdinFilename      string
+	StdinCommand       bool
+	StdinArchive       string
+	CommandFiles       []string
+	Tags               restic.TagLists
+	Labels             []string
+	Description        string
+	DescriptionFile    string
+	Host               string
+	FilesFrom          []string
+	FilesFromVerbatim  []string
+	FilesFromRaw       []string
+	ChangedFilesFrom   []string
+	TimeStamp          string
+	WithAtime          bool
+	WithInodeFlags     bool
+	IgnoreInode        bool
+	IgnoreCtime        bool
+	UseFsSnapshot      bool
+	FsSnapshot         fs.SnapshotProviderOptions
+	DryRun             bool
+	ReadConcurrency    uint
+	NoScan             bool
+	SkipIfUnchanged    bool
+	RetryChangedFiles  uint
+	MaxDuration        time.Duration
+	CheckpointInterval time.Duration
+	FileHash           string
+	Dereference        bool
+	DereferenceArgs    bool
+	PreCommand         string
+	PostCommand        string
+	OnFailureCommand   string
 }
 
 var backupOptions BackupOptions
@@ -100,19 +127,32 @@ func init() {
 	f := cmdBackup.Flags()
 	f.StringVar(&backupOptions.Parent, "parent", "", "use this parent `snapshot` (default: latest snapshot in the group determined by --group-by and not newer than the timestamp determined by --time)")
 	backupOptions.GroupBy = restic.SnapshotGroupByOptions{Host: true, Path: true}
-	f.VarP(&backupOptions.GroupBy, "group-by", "g", "`group` snapshots by host, paths and/or tags, separated by comma (disable grouping with '')")
+	f.VarP(&backupOptions.GroupBy, "group-by", "g", "`group` snapshots by host, paths, tags and/or label:key, separated by comma (disable grouping with '')")
 	f.BoolVarP(&backupOptions.Force, "force", "f", false, `force re-reading the target files/directories (overrides the "parent" flag)`)
 
 	initExcludePatternOptions(f, &backupOptions.excludePatternOptions)
+	initXattrFilterOptions(f, &backupOptions.xattrFilterOptions, "save")
 
 	f.BoolVarP(&backupOptions.ExcludeOtherFS, "one-file-system", "x", false, "exclude other file systems, don't cross filesystem boundaries and subvolumes")
+	f.StringSliceVar(&backupOptions.ExcludeFSTypes, "exclude-fs-type", nil, "exclude files on file systems of the given `types` in the format `type[,type,...]`, e.g. tmpfs,proc,nfs (Linux only, can be specified multiple times)")
 	f.StringArrayVar(&backupOptions.ExcludeIfPresent, "exclude-if-present", nil, "takes `filename[:header]`, exclude contents of directories containing filename (except filename itself) if header of that file is as provided (can be specified multiple times)")
 	f.BoolVar(&backupOptions.ExcludeCaches, "exclude-caches", false, `excludes cache directories that are marked with a CACHEDIR.TAG file. See https://bford.info/cachedir/ for the Cache Directory Tagging Standard`)
+	f.StringArrayVar(&backupOptions.IgnoreFileNames, "ignore-file-name", nil, "exclude files matching the gitignore-style patterns in files called `name` in their directory or a parent directory (can be specified multiple times)")
 	f.StringVar(&backupOptions.ExcludeLargerThan, "exclude-larger-than", "", "max `size` of the files to be backed up (allowed suffixes: k/K, m/M, g/G, t/T)")
+	f.Var(&backupOptions.ExcludeOlderThan, "exclude-older-than", "exclude files which have last been modified more than `duration` ago (e.g. 1y5m7d2h)")
+	f.Var(&backupOptions.ExcludeNewerThan, "exclude-newer-than", "exclude files which have been modified within the last `duration` (e.g. 1y5m7d2h)")
+	f.StringArrayVar(&backupOptions.ExcludeOwners, "exclude-owner", nil, "exclude files and directories owned by `user` (name or ID, can be specified multiple times)")
+	f.StringSliceVar(&backupOptions.ExcludeTypes, "exclude-type", nil, "exclude files of the given `types` in the format `type[,type,...]` (symlink, fifo, socket, device, blockdev, chardev; can be specified multiple times)")
+	f.BoolVar(&backupOptions.ExcludeNoDump, "exclude-nodump", false, "exclude files and directories which have the nodump flag set, see chattr(1) (Linux only)")
 	f.BoolVar(&backupOptions.Stdin, "stdin", false, "read backup from stdin")
 	f.StringVar(&backupOptions.StdinFilename, "stdin-filename", "stdin", "`filename` to use when reading from stdin")
 	f.BoolVar(&backupOptions.StdinCommand, "stdin-from-command", false, "interpret arguments as command to execute and store its stdout")
+	f.StringVar(&backupOptions.StdinArchive, "stdin-archive", "", "read a tar or zip archive from stdin (or the command output) and store its content, `format` is tar or zip")
+	f.StringArrayVar(&backupOptions.CommandFiles, "command-file", nil, "store the output of a command as a file, in the format `name=command` (can be combined with file args; can be specified multiple times)")
 	f.Var(&backupOptions.Tags, "tag", "add `tags` for the new snapshot in the format `tag[,tag,...]` (can be specified multiple times)")
+	f.StringVar(&backupOptions.Description, "description", "", "set the description of the new snapshot to `text`")
+	f.StringVar(&backupOptions.DescriptionFile, "description-file", "", "read the description of the new snapshot from `file`")
+	f.StringArrayVar(&backupOptions.Labels, "label", nil, "add a label in the format `key=value` to the new snapshot (can be specified multiple times)")
 	f.UintVar(&backupOptions.ReadConcurrency, "read-concurrency", 0, "read `n` files concurrently (default: $RESTIC_READ_CONCURRENCY or 2)")
 	f.StringVarP(&backupOptions.Host, "host", "H", "", "set the `hostname` for the snapshot manually. To prevent an expensive rescan use the \"parent\" flag")
 	f.StringVar(&backupOptions.Host, "hostname", "", "set the `hostname` for the snapshot manually")
@@ -124,14 +164,31 @@ func init() {
 	f.StringArrayVar(&backupOptions.FilesFrom, "files-from", nil, "read the files to backup from `file` (can be combined with file args; can be specified multiple times)")
 	f.StringArrayVar(&backupOptions.FilesFromVerbatim, "files-from-verbatim", nil, "read the files to backup from `file` (can be combined with file args; can be specified multiple times)")
 	f.StringArrayVar(&backupOptions.FilesFromRaw, "files-from-raw", nil, "read the files to backup from `file` (can be combined with file args; can be specified multiple times)")
+	f.StringArrayVar(&backupOptions.ChangedFilesFrom, "changed-files-from", nil, "only read the files and directories listed in `file` and take everything else from the parent snapshot (can be specified multiple times)")
 	f.StringVar(&backupOptions.TimeStamp, "time", "", "`time` of the backup (ex. '2012-11-01 22:08:41') (default: now)")
 	f.BoolVar(&backupOptions.WithAtime, "with-atime", false, "store the atime for all files and directories")
+	f.BoolVar(&backupOptions.WithInodeFlags, "with-inode-flags", false, "store the inode flags and the birth time of all files and directories (Linux only)")
 	f.BoolVar(&backupOptions.IgnoreInode, "ignore-inode", false, "ignore inode number and ctime changes when checking for modified files")
 	f.BoolVar(&backupOptions.IgnoreCtime, "ignore-ctime", false, "ignore ctime changes when checking for modified files")
 	f.BoolVarP(&backupOptions.DryRun, "dry-run", "n", false, "do not upload or write any data, just show what would be done")
 	f.BoolVar(&backupOptions.NoScan, "no-scan", false, "do not run scanner to estimate size of backup")
-	if runtime.GOOS == "windows" {
-		f.BoolVar(&backupOptions.UseFsSnapshot, "use-fs-snapshot", false, "use filesystem snapshot where possible (currently only Windows VSS)")
+	f.BoolVar(&backupOptions.SkipIfUnchanged, "skip-if-unchanged", false, "skip snapshot creation if identical to parent snapshot")
+	f.DurationVar(&backupOptions.MaxDuration, "max-duration", 0, "stop reading new files after `duration` and save a partial snapshot, takes a value like 25m or 2h (default: no limit)")
+	f.DurationVar(&backupOptions.CheckpointInterval, "checkpoint-interval", 0, "save a checkpoint snapshot of the progress every `interval`, takes a value like 30m (default: no checkpoints)")
+	f.BoolVar(&backupOptions.Dereference, "dereference", false, "save the files and directories symbolic links point to instead of the links")
+	f.BoolVar(&backupOptions.DereferenceArgs, "dereference-args", false, "like --dereference, but only for symbolic links given on the command line")
+	f.StringVar(&backupOptions.FileHash, "file-hash", "", "store a checksum of the whole content of each file computed with `algorithm` (supported: sha256)")
+	f.UintVar(&backupOptions.RetryChangedFiles, "retry-changed-files", 0, "read files which are modified while reading them up to `n` more times, then mark them in the snapshot (default: 0, do not check for modifications)")
+	f.StringVar(&backupOptions.PreCommand, "pre-command", "", "run `command` before reading any files, a non-zero exit status aborts the backup")
+	f.StringVar(&backupOptions.PostCommand, "post-command", "", "run `command` after the snapshot has been saved")
+	f.StringVar(&backupOptions.OnFailureCommand, "on-failure-command", "", "run `command` if the backup fails or is incomplete")
+	if runtime.GOOS == "windows" || runtime.GOOS == "linux" {
+		f.BoolVar(&backupOptions.UseFsSnapshot, "use-fs-snapshot", false, "use filesystem snapshot where possible (Windows VSS, or see --fs-snapshot-provider on Linux)")
+	}
+	if runtime.GOOS == "linux" {
+		f.StringVar(&backupOptions.FsSnapshot.Provider, "fs-snapshot-provider", "auto", "create filesystem snapshots using `provider` (auto, btrfs, lvm or command)")
+		f.StringVar(&backupOptions.FsSnapshot.CreateCommand, "fs-snapshot-create-command", "", "shell `command` which creates a filesystem snapshot and prints its path, for --fs-snapshot-provider command")
+		f.StringVar(&backupOptions.FsSnapshot.DestroyCommand, "fs-snapshot-destroy-command", "", "shell `command` which removes a filesystem snapshot, for --fs-snapshot-provider command")
 	}
 
 	// parse read concurrency from env, on error the default value will be used
@@ -252,12 +309,47 @@ func readFilenamesRaw(r io.Reader) (names []string, err error) {
 
 // Check returns an error when an invalid combination of options was set.
 func (opts BackupOptions) Check(gopts GlobalOptions, args []string) error {
+	if _, err := restic.ParseLabels(opts.Labels); err != nil {
+		return errors.Fatalf("invalid --label: %v", err)
+	}
+
+	if err := opts.xattrFilterOptions.Check(); err != nil {
+		return err
+	}
+
+	if err := checkHookCommands(opts); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "linux" && opts.UseFsSnapshot {
+		if _, err := fs.NewSnapshotProvider(opts.FsSnapshot); err != nil {
+			return errors.Fatalf("%v", err)
+		}
+	}
+
+	if opts.FileHash != "" {
+		if _, err := restic.NewFileHash(opts.FileHash); err != nil {
+			return errors.Fatalf("invalid --file-hash: %v", err)
+		}
+	}
+
+	if _, err := parseCommandFiles(opts.CommandFiles); err != nil {
+		return err
+	}
+	if len(opts.CommandFiles) > 0 && len(opts.ChangedFilesFrom) > 0 {
+		return errors.Fatal("--command-file and --changed-files-from cannot be used together")
+	}
+
+	if opts.StdinArchive != "" && opts.StdinArchive != "tar" && opts.StdinArchive != "zip" {
+		return errors.Fatalf("invalid archive format %q for --stdin-archive, must be tar or zip", opts.StdinArchive)
+	}
+
 	if gopts.password == "" {
-		if opts.Stdin {
+		if opts.Stdin || (opts.StdinArchive != "" && !opts.StdinCommand) {
 			return errors.Fatal("cannot read both password and data from stdin")
 		}
 
-		filesFrom := append(append(opts.FilesFrom, opts.FilesFromVerbatim...), opts.FilesFromRaw...)
+		filesFrom := append(append(append(opts.FilesFrom, opts.FilesFromVerbatim...), opts.FilesFromRaw...), opts.ChangedFilesFrom...)
 		for _, filename := range filesFrom {
 			if filename == "-" {
 				return errors.Fatal("unable to read password from stdin when data is to be read from stdin, use --password-file or $RESTIC_PASSWORD")
@@ -265,7 +357,7 @@ func (opts BackupOptions) Check(gopts GlobalOptions, args []string) error {
 		}
 	}
 
-	if opts.Stdin || opts.StdinCommand {
+	if opts.Stdin || opts.StdinCommand || opts.StdinArchive != "" {
 		if len(opts.FilesFrom) > 0 {
 			return errors.Fatal("--stdin and --files-from cannot be used together")
 		}
@@ -275,6 +367,12 @@ func (opts BackupOptions) Check(gopts GlobalOptions, args []string) error {
 		if len(opts.FilesFromRaw) > 0 {
 			return errors.Fatal("--stdin and --files-from-raw cannot be used together")
 		}
+		if len(opts.ChangedFilesFrom) > 0 {
+			return errors.Fatal("--stdin and --changed-files-from cannot be used together")
+		}
+		if len(opts.CommandFiles) > 0 {
+			return errors.Fatal("--stdin and --command-file cannot be used together")
+		}
 
 		if len(args) > 0 && !opts.StdinCommand {
 			return errors.Fatal("--stdin was specified and files/dirs were listed as arguments")
@@ -284,9 +382,39 @@ func (opts BackupOptions) Check(gopts GlobalOptions, args []string) error {
 	return nil
 }
 
+// snapshotExcludes returns the exclude patterns and the exclude rules based on
+// file metadata which are recorded in the snapshot.
+func (opts BackupOptions) snapshotExcludes() []string {
+	excludes := append([]string(nil), opts.Excludes...)
+
+	if !opts.ExcludeOlderThan.Zero() {
+		excludes = append(excludes, "--exclude-older-than="+opts.ExcludeOlderThan.String())
+	}
+	if !opts.ExcludeNewerThan.Zero() {
+		excludes = append(excludes, "--exclude-newer-than="+opts.ExcludeNewerThan.String())
+	}
+	for _, owner := range opts.ExcludeOwners {
+		excludes = append(excludes, "--exclude-owner="+owner)
+	}
+	if len(opts.ExcludeTypes) != 0 {
+		excludes = append(excludes, "--exclude-type="+strings.Join(opts.ExcludeTypes, ","))
+	}
+	if opts.ExcludeNoDump {
+		excludes = append(excludes, "--exclude-nodump")
+	}
+	for _, pattern := range opts.IncludeXattrs {
+		excludes = append(excludes, "--include-xattr="+pattern)
+	}
+	for _, pattern := range opts.ExcludeXattrs {
+		excludes = append(excludes, "--exclude-xattr="+pattern)
+	}
+
+	return excludes
+}
+
 // collectRejectByNameFuncs returns a list of all functions which may reject data
 // from being saved in a snapshot based on path only
-func collectRejectByNameFuncs(opts BackupOptions, repo *repository.Repository) (fs []RejectByNameFunc, err error) {
+func collectRejectByNameFuncs(opts BackupOptions, repo *repository.Repository, targets []string, filesystem fs.FS) (fs []RejectByNameFunc, err error) {
 	// exclude restic cache
 	if repo.Cache != nil {
 		f, err := rejectResticCache(repo)
@@ -316,15 +444,32 @@ func collectRejectByNameFuncs(opts BackupOptions, repo *repository.Repository) (
 		fs = append(fs, f)
 	}
 
+	for _, name := range opts.IgnoreFileNames {
+		f, err := rejectByIgnoreFile(name, targets, filesystem)
+		if err != nil {
+			return nil, err
+		}
+
+		fs = append(fs, f)
+	}
+
 	return fs, nil
 }
 
 // collectRejectFuncs returns a list of all functions which may reject data
 // from being saved in a snapshot based on path and file info
-func collectRejectFuncs(opts BackupOptions, targets []string) (fs []RejectFunc, err error) {
+func collectRejectFuncs(opts BackupOptions, targets []string, filesystem fs.FS, now time.Time) (fs []RejectFunc, err error) {
 	// allowed devices
-	if opts.ExcludeOtherFS && !opts.Stdin {
-		f, err := rejectByDevice(targets)
+	if opts.ExcludeOtherFS && !opts.Stdin && opts.StdinArchive == "" {
+		f, err := rejectByDevice(targets, filesystem)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+
+	if len(opts.ExcludeFSTypes) != 0 && !opts.Stdin && opts.StdinArchive == "" {
+		f, err := rejectByFSType(opts.ExcludeFSTypes, filesystem)
 		if err != nil {
 			return nil, err
 		}
@@ -339,12 +484,49 @@ func collectRejectFuncs(opts BackupOptions, targets []string) (fs []RejectFunc,
 		fs = append(fs, f)
 	}
 
+	// the remaining rules are useless for a single file read from stdin
+	if (opts.Stdin || opts.StdinCommand) && opts.StdinArchive == "" {
+		return fs, nil
+	}
+
+	if !opts.ExcludeOlderThan.Zero() {
+		fs = append(fs, rejectOlderThan(opts.ExcludeOlderThan, now))
+	}
+
+	if !opts.ExcludeNewerThan.Zero() {
+		fs = append(fs, rejectNewerThan(opts.ExcludeNewerThan, now))
+	}
+
+	if len(opts.ExcludeOwners) != 0 {
+		f, err := rejectByOwner(opts.ExcludeOwners)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+
+	if len(opts.ExcludeTypes) != 0 {
+		f, err := rejectByType(opts.ExcludeTypes)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+
+	if opts.ExcludeNoDump {
+		f, err := rejectNoDump(filesystem)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+
 	return fs, nil
 }
 
 // collectTargets returns a list of target files/dirs from several sources.
 func collectTargets(opts BackupOptions, args []string) (targets []string, err error) {
-	if opts.Stdin || opts.StdinCommand {
+	if opts.Stdin || opts.StdinCommand || opts.StdinArchive != "" {
 		return nil, nil
 	}
 
@@ -397,6 +579,10 @@ func collectTargets(opts BackupOptions, args []string) (targets []string, err er
 	// Merge args into files-from so we can reuse the normal args checks
 	// and have the ability to use both files-from and args at the same time.
 	targets = append(targets, args...)
+	if len(targets) == 0 && len(opts.CommandFiles) > 0 {
+		// only the output of the commands is saved
+		return nil, nil
+	}
 	if len(targets) == 0 && !opts.Stdin {
 		return nil, errors.Fatal("nothing to backup, please specify target files/dirs")
 	}
@@ -409,6 +595,91 @@ func collectTargets(opts BackupOptions, args []string) (targets []string, err er
 	return targets, nil
 }
 
+// readChangedFiles reads the lists of changed files, one path per line.
+func readChangedFiles(filesys fs.FS, filenames []string) (*archiver.ChangedFiles, error) {
+	var paths []string
+	for _, filename := range filenames {
+		lines, err := readLines(filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			if line == "" {
+				continue
+			}
+			paths = append(paths, line)
+		}
+	}
+
+	return archiver.NewChangedFiles(filesys, paths)
+}
+
+// commandFile is a virtual file specified via --command-file, which contains
+// the standard output of a command.
+type commandFile struct {
+	target string
+	args   []string
+}
+
+// parseCommandFiles parses the values of --command-file. The files are located
+// at the given name below the root directory.
+func parseCommandFiles(values []string) ([]commandFile, error) {
+	var files []commandFile
+	seen := make(map[string]struct{})
+	for _, value := range values {
+		name, command, ok := strings.Cut(value, "=")
+		if !ok || strings.Trim(name, "/") == "" {
+			return nil, errors.Fatalf("invalid --command-file %q, must be in the format name=command", value)
+		}
+
+		args, err := backend.SplitShellStrings(command)
+		if err != nil {
+			return nil, errors.Fatalf("invalid --command-file %q: %v", value, err)
+		}
+
+		target := filepath.FromSlash(path.Join("/", name))
+		if _, ok := seen[target]; ok {
+			return nil, errors.Fatalf("duplicate filename %v for --command-file", target)
+		}
+		seen[target] = struct{}{}
+
+		files = append(files, commandFile{target: target, args: args})
+	}
+	return files, nil
+}
+
+// readArchive reads an archive in the given format from source and returns a
+// file system containing its entries. source is closed afterwards.
+func readArchive(format string, source io.ReadCloser) (*fs.Archive, error) {
+	var archiveFS *fs.Archive
+	var err error
+	switch format {
+	case "tar":
+		archiveFS, err = fs.NewTarArchive(source)
+	case "zip":
+		archiveFS, err = fs.NewZipArchive(source)
+	default:
+		err = errors.Fatalf("invalid archive format %q", format)
+	}
+
+	// for a command, this reports a non-zero exit code
+	cerr := source.Close()
+	if err == nil && cerr != nil {
+		_ = archiveFS.Close()
+		err = cerr
+	}
+	if err != nil {
+		return nil, errors.Fatalf("unable to read archive: %v", err)
+	}
+
+	if len(archiveFS.Targets()) == 0 {
+		_ = archiveFS.Close()
+		return nil, errors.Fatal("archive is empty")
+	}
+
+	return archiveFS, nil
+}
+
 // parent returns the ID of the parent snapshot. If there is none, nil is
 // returned.
 func findParentSnapshot(ctx context.Context, repo restic.ListerLoaderUnpacked, opts BackupOptions, targets []string, timeStampLimit time.Time) (*restic.Snapshot, error) {
@@ -430,6 +701,19 @@ func findParentSnapshot(ctx context.Context, repo restic.ListerLoaderUnpacked, o
 	if opts.GroupBy.Tag {
 		f.Tags = []restic.TagList{opts.Tags.Flatten()}
 	}
+	if len(opts.GroupBy.Labels) > 0 {
+		labels, err := restic.ParseLabels(opts.Labels)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range opts.GroupBy.Labels {
+			if value, ok := labels[key]; ok {
+				f.Labels = append(f.Labels, key+"="+value)
+			} else {
+				f.MissingLabels = append(f.MissingLabels, key)
+			}
+		}
+	}
 
 	sn, _, err := f.FindLatest(ctx, repo, repo, snName)
 	// Snapshot not found is ok if no explicit parent was set
@@ -439,8 +723,10 @@ func findParentSnapshot(ctx context.Context, repo restic.ListerLoaderUnpacked, o
 	return sn, err
 }
 
-func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, term *termstatus.Terminal, args []string) error {
-	err := opts.Check(gopts, args)
+func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, term *termstatus.Terminal, args []string) (err error) {
+	backupStart := time.Now()
+
+	err = opts.Check(gopts, args)
 	if err != nil {
 		return err
 	}
@@ -450,6 +736,15 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		return err
 	}
 
+	commandFiles, err := parseCommandFiles(opts.CommandFiles)
+	if err != nil {
+		return err
+	}
+	var commandTargets []string
+	for _, cf := range commandFiles {
+		commandTargets = append(commandTargets, cf.target)
+	}
+
 	timeStamp := time.Now()
 	if opts.TimeStamp != "" {
 		timeStamp, err = time.ParseInLocation(TimeFormat, opts.TimeStamp, time.Local)
@@ -458,11 +753,7 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		}
 	}
 
-	if gopts.verbosity >= 2 && !gopts.JSON {
-		Verbosef("open repository\n")
-	}
-
-	repo, err := OpenRepository(ctx, gopts)
+	description, err := readDescription(opts.Description, opts.DescriptionFile)
 	if err != nil {
 		return err
 	}
@@ -477,6 +768,22 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		calculateProgressInterval(!gopts.Quiet, gopts.JSON))
 	defer progressReporter.Done()
 
+	hooks := newBackupHooks(opts, gopts, progressPrinter)
+	defer func() {
+		if err != nil {
+			hooks.runOnFailure(err)
+		}
+	}()
+
+	if gopts.verbosity >= 2 && !gopts.JSON {
+		Verbosef("open repository\n")
+	}
+
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
 	if opts.DryRun {
 		repo.SetDryRun()
 	}
@@ -493,21 +800,10 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		}
 	}
 
-	// rejectByNameFuncs collect functions that can reject items from the backup based on path only
-	rejectByNameFuncs, err := collectRejectByNameFuncs(opts, repo)
-	if err != nil {
-		return err
-	}
-
-	// rejectFuncs collect functions that can reject items from the backup based on path and file info
-	rejectFuncs, err := collectRejectFuncs(opts, targets)
-	if err != nil {
-		return err
-	}
-
 	var parentSnapshot *restic.Snapshot
-	if !opts.Stdin {
-		parentSnapshot, err = findParentSnapshot(ctx, repo, opts, targets, timeStamp)
+	if !opts.Stdin && opts.StdinArchive == "" {
+		parentTargets := append(append([]string(nil), targets...), commandTargets...)
+		parentSnapshot, err = findParentSnapshot(ctx, repo, opts, parentTargets, timeStamp)
 		if err != nil {
 			return err
 		}
@@ -532,22 +828,20 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		return err
 	}
 
-	selectByNameFilter := func(item string) bool {
-		for _, reject := range rejectByNameFuncs {
-			if reject(item) {
-				return false
-			}
-		}
-		return true
+	errorHandler := func(item string, err error) error {
+		return progressReporter.Error(item, err)
 	}
 
-	selectFilter := func(item string, fi os.FileInfo) bool {
-		for _, reject := range rejectFuncs {
-			if reject(item, fi) {
-				return false
-			}
+	messageHandler := func(msg string, args ...interface{}) {
+		if !gopts.JSON {
+			progressPrinter.P(msg, args...)
 		}
-		return true
+	}
+
+	// run the pre-command before creating file system snapshots, such that it
+	// can e.g. flush databases to disk
+	if err = hooks.runPre(ctx); err != nil {
+		return err
 	}
 
 	var targetFS fs.FS = fs.Local{}
@@ -556,22 +850,88 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 			return err
 		}
 
-		errorHandler := func(item string, err error) error {
-			return progressReporter.Error(item, err)
+		localVss := fs.NewLocalVss(errorHandler, messageHandler)
+		defer localVss.DeleteSnapshots()
+		targetFS = localVss
+	}
+
+	if runtime.GOOS == "linux" && opts.UseFsSnapshot && !opts.Stdin && !opts.StdinCommand && opts.StdinArchive == "" {
+		provider, err := fs.NewSnapshotProvider(opts.FsSnapshot)
+		if err != nil {
+			return errors.Fatalf("%v", err)
 		}
 
-		messageHandler := func(msg string, args ...interface{}) {
-			if !gopts.JSON {
-				progressPrinter.P(msg, args...)
+		localSnapshot, err := fs.NewLocalSnapshot(provider, errorHandler, messageHandler)
+		if err != nil {
+			return err
+		}
+		// also remove the snapshots if restic is interrupted
+		AddCleanupHandler(func(code int) (int, error) {
+			localSnapshot.DeleteSnapshots()
+			return code, nil
+		})
+		defer localSnapshot.DeleteSnapshots()
+
+		if err := localSnapshot.CreateSnapshots(ctx, targets); err != nil {
+			return err
+		}
+		targetFS = localSnapshot
+	}
+
+	isCommandFile := make(map[string]bool)
+	if len(commandFiles) > 0 {
+		commandFS := fs.NewCommandFiles(ctx, targetFS, timeStamp, globalOptions.stderr)
+		for _, cf := range commandFiles {
+			if err := commandFS.Add(cf.target, cf.args); err != nil {
+				return errors.Fatalf("invalid --command-file: %v", err)
 			}
+
+			abstarget, err := commandFS.Abs(cf.target)
+			if err != nil {
+				return err
+			}
+			isCommandFile[cf.target] = true
+			isCommandFile[abstarget] = true
 		}
+		targetFS = commandFS
+	}
 
-		localVss := fs.NewLocalVss(errorHandler, messageHandler)
-		defer localVss.DeleteSnapshots()
-		targetFS = localVss
+	// rejectByNameFuncs collect functions that can reject items from the backup based on path only
+	rejectByNameFuncs, err := collectRejectByNameFuncs(opts, repo, targets, targetFS)
+	if err != nil {
+		return err
+	}
+
+	selectByNameFilter := func(item string) bool {
+		for _, reject := range rejectByNameFuncs {
+			if reject(item) {
+				return false
+			}
+		}
+		return true
 	}
 
-	if opts.Stdin || opts.StdinCommand {
+	// rejectFuncs collect functions that can reject items from the backup based on path and file info
+	rejectFuncs, err := collectRejectFuncs(opts, targets, targetFS, backupStart)
+	if err != nil {
+		return err
+	}
+	targets = append(targets, commandTargets...)
+
+	selectFilter := func(item string, fi os.FileInfo) bool {
+		// the rules based on file metadata do not apply to the output of commands
+		if isCommandFile[item] {
+			return true
+		}
+		for _, reject := range rejectFuncs {
+			if reject(item, fi) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if opts.Stdin || opts.StdinCommand || opts.StdinArchive != "" {
 		if !gopts.JSON {
 			progressPrinter.V("read data from stdin")
 		}
@@ -583,25 +943,43 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 				return err
 			}
 		}
-		targetFS = &fs.Reader{
-			ModTime:    timeStamp,
-			Name:       filename,
-			Mode:       0644,
-			ReadCloser: source,
+
+		if opts.StdinArchive != "" {
+			archiveFS, err := readArchive(opts.StdinArchive, source)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := archiveFS.Close(); err != nil {
+					Warnf("unable to remove temporary file: %v\n", err)
+				}
+			}()
+			targetFS = archiveFS
+			targets = archiveFS.Targets()
+		} else {
+			targetFS = &fs.Reader{
+				ModTime:    timeStamp,
+				Name:       filename,
+				Mode:       0644,
+				ReadCloser: source,
+			}
+			targets = []string{filename}
 		}
-		targets = []string{filename}
 	}
 
 	wg, wgCtx := errgroup.WithContext(ctx)
 	cancelCtx, cancel := context.WithCancel(wgCtx)
 	defer cancel()
 
-	if !opts.NoScan {
+	// the scanner would traverse all files, which --changed-files-from tries to avoid
+	if !opts.NoScan && len(opts.ChangedFilesFrom) == 0 {
 		sc := archiver.NewScanner(targetFS)
 		sc.SelectByName = selectByNameFilter
 		sc.Select = selectFilter
 		sc.Error = progressPrinter.ScannerError
 		sc.Result = progressReporter.ReportTotal
+		sc.Dereference = opts.Dereference
+		sc.DereferenceArgs = opts.DereferenceArgs
 
 		if !gopts.JSON {
 			progressPrinter.V("start scan on %v", targets)
@@ -613,6 +991,9 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 	arch.SelectByName = selectByNameFilter
 	arch.Select = selectFilter
 	arch.WithAtime = opts.WithAtime
+	arch.WithInodeFlags = opts.WithInodeFlags
+	arch.Dereference = opts.Dereference
+	arch.DereferenceArgs = opts.DereferenceArgs
 	success := true
 	arch.Error = func(item string, err error) error {
 		success = false
@@ -624,6 +1005,7 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		}
 		return reterr
 	}
+	arch.Warn = Warnf
 	arch.CompleteItem = progressReporter.CompleteItem
 	arch.StartFile = progressReporter.StartFile
 	arch.CompleteBlob = progressReporter.CompleteBlob
@@ -636,20 +1018,51 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 	if opts.IgnoreCtime {
 		arch.ChangeIgnoreFlags |= archiver.ChangeIgnoreCtime
 	}
+	arch.RetryChangedFiles = opts.RetryChangedFiles
+	arch.FileHash = opts.FileHash
+	arch.SelectXattr = opts.xattrFilterOptions.SelectFunc()
+	if opts.MaxDuration > 0 {
+		arch.Deadline = backupStart.Add(opts.MaxDuration)
+	}
+	if !opts.DryRun {
+		arch.CheckpointInterval = opts.CheckpointInterval
+	}
+
+	if len(opts.ChangedFilesFrom) > 0 {
+		if parentSnapshot == nil {
+			if !gopts.JSON {
+				progressPrinter.P("no parent snapshot found, ignoring --changed-files-from\n")
+			}
+		} else {
+			arch.ChangedFiles, err = readChangedFiles(targetFS, opts.ChangedFilesFrom)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	labels, err := restic.ParseLabels(opts.Labels)
+	if err != nil {
+		return err
+	}
 
 	snapshotOpts := archiver.SnapshotOptions{
-		Excludes:       opts.Excludes,
-		Tags:           opts.Tags.Flatten(),
-		Time:           timeStamp,
-		Hostname:       opts.Host,
-		ParentSnapshot: parentSnapshot,
-		ProgramVersion: "restic " + version,
+		Excludes:        opts.snapshotExcludes(),
+		Tags:            opts.Tags.Flatten(),
+		Labels:          labels,
+		Description:     description,
+		Time:            timeStamp,
+		Hostname:        opts.Host,
+		ParentSnapshot:  parentSnapshot,
+		ProgramVersion:  "restic " + version,
+		BackupStart:     backupStart,
+		SkipIfUnchanged: opts.SkipIfUnchanged,
 	}
 
 	if !gopts.JSON {
 		progressPrinter.V("start backup on %v", targets)
 	}
-	_, id, err := arch.Snapshot(ctx, targets, snapshotOpts)
+	sn, id, summary, err := arch.Snapshot(ctx, targets, snapshotOpts)
 
 	// cleanly shutdown all running goroutines
 	cancel()
@@ -663,10 +1076,25 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 	}
 
 	// Report finished execution
-	progressReporter.Finish(id, opts.DryRun)
+	progressReporter.Finish(id, summary, opts.DryRun)
 	if !gopts.JSON && !opts.DryRun {
-		progressPrinter.P("snapshot %s saved\n", id.Str())
+		if id.IsNull() {
+			progressPrinter.P("skipped creating snapshot, no changes compared to parent snapshot\n")
+		} else if len(sn.Unvisited) > 0 {
+			progressPrinter.P("time limit of %v exceeded, %d paths were not backed up completely\n", opts.MaxDuration, len(sn.Unvisited))
+			progressPrinter.P("partial snapshot %s saved\n", id.Str())
+		} else {
+			progressPrinter.P("snapshot %s saved\n", id.Str())
+		}
 	}
+
+	exitStatus := 0
+	if !success {
+		exitStatus = 3
+	}
+	hooks.snapshotID, hooks.summary = id, summary
+	hooks.runPost(ctx, exitStatus)
+
 	if !success {
 		return ErrInvalidSourceData
 	}
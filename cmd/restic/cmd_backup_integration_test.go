@@ -1,15 +1,21 @@
 package main
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/fs"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
@@ -340,6 +346,42 @@ func TestBackupExclude(t *testing.T) {
 		"expected file %q not in first snapshot, but it's included", "passwords.txt")
 }
 
+func TestBackupExcludeByMetadata(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testRunInit(t, env.gopts)
+
+	datadir := filepath.Join(env.base, "testdata")
+	rtest.OK(t, os.MkdirAll(filepath.Join(datadir, "dir"), 0755))
+	for _, filename := range []string{"old", "new", "dir/old"} {
+		rtest.OK(t, os.WriteFile(filepath.Join(datadir, filename), []byte(filename), 0644))
+	}
+	old := time.Now().AddDate(-2, 0, 0)
+	for _, filename := range []string{"old", "dir/old", "dir"} {
+		rtest.OK(t, os.Chtimes(filepath.Join(datadir, filename), old, old))
+	}
+
+	opts := BackupOptions{ExcludeOlderThan: restic.Duration{Years: 1}}
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	newest, _ := testRunSnapshots(t, env.gopts)
+	files := testRunLs(t, env.gopts, newest.ID.String())
+	rtest.Assert(t, includes(files, "/testdata/new") && includes(files, "/testdata/dir"),
+		"expected new files and directories in snapshot, got %v", files)
+	rtest.Assert(t, !includes(files, "/testdata/old") && !includes(files, "/testdata/dir/old"),
+		"expected old files not in snapshot, got %v", files)
+	rtest.Equals(t, []string{"--exclude-older-than=1y"}, newest.Excludes)
+
+	opts = BackupOptions{ExcludeNewerThan: restic.Duration{Days: 1}, ExcludeTypes: []string{"fifo", "socket"}}
+	opts.Excludes = []string{"*.tmp"}
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	files = testRunLs(t, env.gopts, newest.ID.String())
+	rtest.Assert(t, includes(files, "/testdata/old") && !includes(files, "/testdata/new"),
+		"expected only old files in snapshot, got %v", files)
+	rtest.Equals(t, []string{"*.tmp", "--exclude-newer-than=1d", "--exclude-type=fifo,socket"}, newest.Excludes)
+}
+
 func TestBackupErrors(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		return
@@ -457,6 +499,54 @@ func TestBackupProgramVersion(t *testing.T) {
 		"expected %v, got %v", resticVersion, newest.ProgramVersion)
 }
 
+func TestBackupSkipIfUnchanged(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{SkipIfUnchanged: true}
+
+	for i := 0; i < 3; i++ {
+		testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+		testListSnapshots(t, env.gopts, 1)
+	}
+
+	// modifying a file must result in a new snapshot
+	rtest.OK(t, appendRandomData(filepath.Join(env.testdata, "0", "0", "9", "0"), 42))
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	testListSnapshots(t, env.gopts, 2)
+
+	testRunCheck(t, env.gopts)
+}
+
+func TestBackupSkipIfUnchangedJSON(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{SkipIfUnchanged: true}
+
+	for i, skipped := range []bool{false, true} {
+		buf := bytes.NewBuffer(nil)
+		gopts := env.gopts
+		gopts.JSON = true
+		gopts.stdout = buf
+		testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, gopts)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		var summary struct {
+			MessageType     string `json:"message_type"`
+			SnapshotID      string `json:"snapshot_id"`
+			SnapshotSkipped bool   `json:"snapshot_skipped"`
+		}
+		rtest.OK(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+		rtest.Equals(t, "summary", summary.MessageType)
+		rtest.Equals(t, skipped, summary.SnapshotSkipped, fmt.Sprintf("backup %d", i))
+		rtest.Equals(t, skipped, summary.SnapshotID == "", fmt.Sprintf("backup %d", i))
+	}
+	testListSnapshots(t, env.gopts, 1)
+}
+
 func TestQuietBackup(t *testing.T) {
 	env, cleanup := withTestEnvironment(t)
 	defer cleanup()
@@ -637,3 +727,203 @@ func TestStdinFromCommandFailNoOutputAndExitCode(t *testing.T) {
 
 	testRunCheck(t, env.gopts)
 }
+
+func TestBackupStdinArchive(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+
+	modTime := time.Date(2023, 5, 17, 10, 20, 30, 0, time.UTC)
+	entries := []struct {
+		hdr     tar.Header
+		content string
+	}{
+		{tar.Header{Typeflag: tar.TypeDir, Name: "dir/", Mode: 0o750}, ""},
+		{tar.Header{Typeflag: tar.TypeReg, Name: "dir/file", Mode: 0o644}, "file content\n"},
+		{tar.Header{Typeflag: tar.TypeSymlink, Name: "dir/link", Linkname: "file", Mode: 0o777}, ""},
+		{tar.Header{Typeflag: tar.TypeReg, Name: "empty", Mode: 0o600}, ""},
+		{tar.Header{Typeflag: tar.TypeReg, Name: "top.txt", Mode: 0o640}, "top level file\n"},
+	}
+
+	input := filepath.Join(env.base, "input.tar")
+	f, err := os.Create(input)
+	rtest.OK(t, err)
+	tw := tar.NewWriter(f)
+	for i := range entries {
+		hdr := &entries[i].hdr
+		hdr.Size = int64(len(entries[i].content))
+		hdr.Uid, hdr.Gid = 1000, 100
+		hdr.Uname, hdr.Gname = "user", "users"
+		hdr.ModTime = modTime.Add(time.Duration(i) * time.Hour)
+		rtest.OK(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(entries[i].content))
+		rtest.OK(t, err)
+	}
+	rtest.OK(t, tw.Close())
+	rtest.OK(t, f.Close())
+
+	stdin, err := os.Open(input)
+	rtest.OK(t, err)
+	defer func(orig *os.File) {
+		os.Stdin = orig
+		_ = stdin.Close()
+	}(os.Stdin)
+	os.Stdin = stdin
+
+	opts := BackupOptions{StdinArchive: "tar"}
+	testRunBackup(t, "", nil, opts, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 1)
+	testRunCheck(t, env.gopts)
+
+	// dumping the snapshot must result in the same archive
+	output := filepath.Join(env.base, "output.tar")
+	rtest.OK(t, runDump(context.TODO(), DumpOptions{Archive: "tar", Target: output}, env.gopts, []string{snapshotIDs[0].String(), "/"}))
+
+	f, err = os.Open(output)
+	rtest.OK(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+	tr := tar.NewReader(f)
+	for _, entry := range entries {
+		hdr, err := tr.Next()
+		rtest.OK(t, err)
+
+		want := entry.hdr
+		rtest.Equals(t, want.Name, hdr.Name)
+		rtest.Equals(t, want.Typeflag, hdr.Typeflag, want.Name)
+		rtest.Equals(t, want.Mode, hdr.Mode, want.Name)
+		rtest.Equals(t, want.Size, hdr.Size, want.Name)
+		rtest.Equals(t, want.Linkname, hdr.Linkname, want.Name)
+		rtest.Equals(t, want.Uid, hdr.Uid, want.Name)
+		rtest.Equals(t, want.Gid, hdr.Gid, want.Name)
+		rtest.Equals(t, want.Uname, hdr.Uname, want.Name)
+		rtest.Equals(t, want.Gname, hdr.Gname, want.Name)
+		rtest.Assert(t, want.ModTime.Equal(hdr.ModTime), "%v: wrong modification time, want %v, got %v", want.Name, want.ModTime, hdr.ModTime)
+
+		content, err := io.ReadAll(tr)
+		rtest.OK(t, err)
+		rtest.Equals(t, entry.content, string(content), want.Name)
+	}
+	_, err = tr.Next()
+	rtest.Equals(t, io.EOF, err)
+}
+
+func TestBackupCommandFiles(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{
+		CommandFiles: []string{
+			"db1.sql=echo first database",
+			"dumps/db2.sql=echo second database",
+		},
+	}
+
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 1)
+	testRunCheck(t, env.gopts)
+
+	restoredir := filepath.Join(env.base, "restore")
+	testRunRestore(t, env.gopts, restoredir, snapshotIDs[0])
+	for name, content := range map[string]string{
+		"db1.sql":       "first database\n",
+		"dumps/db2.sql": "second database\n",
+	} {
+		buf, err := os.ReadFile(filepath.Join(restoredir, filepath.FromSlash(name)))
+		rtest.OK(t, err)
+		rtest.Equals(t, content, string(buf))
+	}
+	_, err := os.Stat(filepath.Join(restoredir, "testdata", "0"))
+	rtest.OK(t, err)
+
+	// a failing command is reported, but the backup completes without its file
+	opts.CommandFiles = append(opts.CommandFiles, "fail=false")
+	err = testRunBackupAssumeFailure(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	rtest.Assert(t, err == ErrInvalidSourceData, "unexpected error %v", err)
+	firstID := snapshotIDs[0]
+	snapshotIDs = testListSnapshots(t, env.gopts, 2)
+	testRunCheck(t, env.gopts)
+
+	for _, id := range snapshotIDs {
+		if id == firstID {
+			continue
+		}
+		restoredir := filepath.Join(env.base, "restore-"+id.Str())
+		testRunRestore(t, env.gopts, restoredir, id)
+		_, err = os.Stat(filepath.Join(restoredir, "db1.sql"))
+		rtest.OK(t, err)
+		_, err = os.Stat(filepath.Join(restoredir, "fail"))
+		rtest.Assert(t, errors.Is(err, os.ErrNotExist), "output of failed command was stored: %v", err)
+	}
+}
+
+func TestBackupHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands use sh")
+	}
+
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	hookDir := rtest.TempDir(t)
+	envCommand := func(name string) string {
+		return fmt.Sprintf(`sh -c "env > '%s'"`, filepath.Join(hookDir, name))
+	}
+	readEnv := func(name string) map[string]string {
+		data, err := os.ReadFile(filepath.Join(hookDir, name))
+		rtest.OK(t, err)
+		vars := make(map[string]string)
+		for _, line := range strings.Split(string(data), "\n") {
+			if k, v, ok := strings.Cut(line, "="); ok && strings.HasPrefix(k, "RESTIC_BACKUP_") {
+				vars[k] = v
+			}
+		}
+		return vars
+	}
+
+	opts := BackupOptions{
+		PreCommand:       envCommand("pre"),
+		PostCommand:      envCommand("post"),
+		OnFailureCommand: envCommand("on-failure"),
+	}
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 1)
+
+	pre := readEnv("pre")
+	rtest.Equals(t, "pre", pre["RESTIC_BACKUP_HOOK"])
+	rtest.Equals(t, env.gopts.Repo, pre["RESTIC_BACKUP_REPOSITORY"])
+	_, ok := pre["RESTIC_BACKUP_SNAPSHOT_ID"]
+	rtest.Assert(t, !ok, "pre-command must not receive a snapshot ID")
+
+	post := readEnv("post")
+	rtest.Equals(t, "post", post["RESTIC_BACKUP_HOOK"])
+	rtest.Equals(t, "0", post["RESTIC_BACKUP_EXIT_STATUS"])
+	rtest.Equals(t, snapshotIDs[0].String(), post["RESTIC_BACKUP_SNAPSHOT_ID"])
+	rtest.Assert(t, post["RESTIC_BACKUP_FILES_NEW"] != "0", "unexpected number of new files %v", post["RESTIC_BACKUP_FILES_NEW"])
+
+	_, err := os.Stat(filepath.Join(hookDir, "on-failure"))
+	rtest.Assert(t, errors.Is(err, os.ErrNotExist), "on-failure command was run for a successful backup")
+
+	// a failing post-command does not affect the saved snapshot
+	opts.PostCommand = "false"
+	testRunBackup(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	testListSnapshots(t, env.gopts, 2)
+	_, err = os.Stat(filepath.Join(hookDir, "on-failure"))
+	rtest.Assert(t, errors.Is(err, os.ErrNotExist), "on-failure command was run for a failing post-command")
+	opts.PostCommand = envCommand("post")
+
+	// a failing pre-command aborts the backup
+	opts.PreCommand = "false"
+	err = testRunBackupAssumeFailure(t, filepath.Dir(env.testdata), []string{"testdata"}, opts, env.gopts)
+	rtest.Assert(t, err != nil, "backup did not fail")
+	testListSnapshots(t, env.gopts, 2)
+
+	onFailure := readEnv("on-failure")
+	rtest.Equals(t, "on-failure", onFailure["RESTIC_BACKUP_HOOK"])
+	rtest.Equals(t, "1", onFailure["RESTIC_BACKUP_EXIT_STATUS"])
+	rtest.Equals(t, err.Error(), onFailure["RESTIC_BACKUP_ERROR"])
+}
@@ -29,6 +29,7 @@ restic find --json --blob 420f620f b46ebe8a ddd38656
 restic find --show-pack-id --blob 420f620f
 restic find --tree 577c2bc9 f81f2e22 a62827a9
 restic find --pack 025c1d06
+restic find --hash sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855
 
 EXIT STATUS
 ===========
@@ -48,6 +49,7 @@ type FindOptions struct {
 	Snapshots          []string
 	BlobID, TreeID     bool
 	PackID, ShowPackID bool
+	FileHash           bool
 	CaseInsensitive    bool
 	ListLong           bool
 	HumanReadable      bool
@@ -66,6 +68,7 @@ func init() {
 	f.BoolVar(&findOptions.BlobID, "blob", false, "pattern is a blob-ID")
 	f.BoolVar(&findOptions.TreeID, "tree", false, "pattern is a tree-ID")
 	f.BoolVar(&findOptions.PackID, "pack", false, "pattern is a pack-ID")
+	f.BoolVar(&findOptions.FileHash, "hash", false, "pattern is a whole-file hash stored with backup --file-hash")
 	f.BoolVar(&findOptions.ShowPackID, "show-pack-id", false, "display the pack-ID the blobs belong to (with --blob or --tree)")
 	f.BoolVarP(&findOptions.CaseInsensitive, "ignore-case", "i", false, "ignore case for pattern")
 	f.BoolVarP(&findOptions.ListLong, "long", "l", false, "use a long listing format showing size and mode")
@@ -250,6 +253,7 @@ type Finder struct {
 	out        statefulOutput
 	blobIDs    map[string]struct{}
 	treeIDs    map[string]struct{}
+	fileHashes map[string]struct{}
 	itemsFound int
 }
 
@@ -365,7 +369,7 @@ func (f *Finder) findIDs(ctx context.Context, sn *restic.Snapshot) error {
 				f.itemsFound++
 				// Terminate if we have found all trees (and we are not
 				// looking for blobs)
-				if f.itemsFound >= len(f.treeIDs) && f.blobIDs == nil {
+				if f.itemsFound >= len(f.treeIDs) && f.blobIDs == nil && f.fileHashes == nil {
 					// Return an error to terminate the Walk
 					return errors.New("OK")
 				}
@@ -388,6 +392,12 @@ func (f *Finder) findIDs(ctx context.Context, sn *restic.Snapshot) error {
 			}
 		}
 
+		if node.Type == "file" && f.fileHashes != nil && node.FileHash != "" {
+			if _, ok := f.fileHashes[node.FileHash]; ok {
+				f.out.PrintObject("file", node.FileHash, nodepath, parentTreeID.String(), sn)
+			}
+		}
+
 		return nil
 	}})
 }
@@ -557,9 +567,13 @@ func runFind(ctx context.Context, opts FindOptions, gopts GlobalOptions, args []
 
 	// Check at most only one kind of IDs is provided: currently we
 	// can't mix types
-	if (opts.BlobID && opts.TreeID) ||
-		(opts.BlobID && opts.PackID) ||
-		(opts.TreeID && opts.PackID) {
+	idTypes := 0
+	for _, set := range []bool{opts.BlobID, opts.TreeID, opts.PackID, opts.FileHash} {
+		if set {
+			idTypes++
+		}
+	}
+	if idTypes > 1 {
 		return errors.Fatal("cannot have several ID types")
 	}
 
@@ -605,6 +619,17 @@ func runFind(ctx context.Context, opts FindOptions, gopts GlobalOptions, args []
 		}
 	}
 
+	if opts.FileHash {
+		f.fileHashes = make(map[string]struct{})
+		for _, pat := range f.pat.pattern {
+			algo, sum, err := restic.ParseFileHash(pat)
+			if err != nil {
+				return errors.Fatalf("invalid --hash pattern: %v", err)
+			}
+			f.fileHashes[restic.FormatFileHash(algo, sum)] = struct{}{}
+		}
+	}
+
 	if opts.PackID {
 		err := f.packsToBlobs(ctx, f.pat.pattern)
 		if err != nil {
@@ -622,7 +647,7 @@ func runFind(ctx context.Context, opts FindOptions, gopts GlobalOptions, args []
 	})
 
 	for _, sn := range filteredSnapshots {
-		if f.blobIDs != nil || f.treeIDs != nil {
+		if f.blobIDs != nil || f.treeIDs != nil || f.fileHashes != nil {
 			if err = f.findIDs(ctx, sn); err != nil && err.Error() != "OK" {
 				return err
 			}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
 
@@ -85,3 +90,37 @@ func TestFindJSON(t *testing.T) {
 	rtest.Assert(t, len(matches[0].Matches) == 3, "expected 3 files to match (%v)", datafile)
 	rtest.Assert(t, matches[0].Hits == 3, "expected hits to show 3 matches (%v)", datafile)
 }
+
+func TestFindFileHash(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testRunInit(t, env.gopts)
+	data := []byte("content with a known hash\n")
+	rtest.OK(t, os.MkdirAll(env.testdata, 0755))
+	rtest.OK(t, os.WriteFile(filepath.Join(env.testdata, "hashed"), data, 0644))
+	rtest.OK(t, os.WriteFile(filepath.Join(env.testdata, "other"), []byte("other content\n"), 0644))
+
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{FileHash: restic.FileHashSHA256}, env.gopts)
+	testRunCheck(t, env.gopts)
+
+	sum := sha256.Sum256(data)
+	buf, err := withCaptureStdout(func() error {
+		gopts := env.gopts
+		gopts.JSON = true
+		opts := FindOptions{FileHash: true}
+		return runFind(context.TODO(), opts, gopts, []string{hex.EncodeToString(sum[:])})
+	})
+	rtest.OK(t, err)
+
+	var objects []struct {
+		ObjectType string `json:"object_type"`
+		ID         string `json:"id"`
+		Path       string `json:"path"`
+	}
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &objects))
+	rtest.Equals(t, 1, len(objects))
+	rtest.Equals(t, "file", objects[0].ObjectType)
+	rtest.Equals(t, restic.FormatFileHash(restic.FileHashSHA256, sum[:]), objects[0].ID)
+	rtest.Assert(t, strings.HasSuffix(objects[0].Path, "/hashed"), "unexpected path %v", objects[0].Path)
+}
@@ -128,7 +128,7 @@ func init() {
 
 	f.BoolVarP(&forgetOptions.Compact, "compact", "c", false, "use compact output format")
 	forgetOptions.GroupBy = restic.SnapshotGroupByOptions{Host: true, Path: true}
-	f.VarP(&forgetOptions.GroupBy, "group-by", "g", "`group` snapshots by host, paths and/or tags, separated by comma (disable grouping with '')")
+	f.VarP(&forgetOptions.GroupBy, "group-by", "g", "`group` snapshots by host, paths, tags and/or label:key, separated by comma (disable grouping with '')")
 	f.BoolVarP(&forgetOptions.DryRun, "dry-run", "n", false, "do not delete anything, just print what would be done")
 	f.BoolVar(&forgetOptions.Prune, "prune", false, "automatically run the 'prune' command if snapshots have been removed")
 
@@ -245,6 +245,7 @@ func runForget(ctx context.Context, opts ForgetOptions, pruneOptions PruneOption
 				fg.Tags = key.Tags
 				fg.Host = key.Hostname
 				fg.Paths = key.Paths
+				fg.Labels = key.Labels
 
 				keep, remove, reasons := restic.ApplyPolicy(snapshotGroup, policy)
 
@@ -313,6 +314,7 @@ type ForgetGroup struct {
 	Tags    []string            `json:"tags"`
 	Host    string              `json:"host"`
 	Paths   []string            `json:"paths"`
+	Labels  map[string]string   `json:"labels,omitempty"`
 	Keep    []Snapshot          `json:"keep"`
 	Remove  []Snapshot          `json:"remove"`
 	Reasons []restic.KeepReason `json:"reasons"`
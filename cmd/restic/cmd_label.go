@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+)
+
+var cmdLabel = &cobra.Command{
+	Use:   "label [flags] [snapshotID ...]",
+	Short: "Modify labels on snapshots",
+	Long: `
+The "label" command allows you to modify the key=value labels on existing
+snapshots.
+
+You can either set/replace the entire set of labels on a snapshot, or
+add labels to/remove labels from the existing set. Adding a label with a key
+which already exists replaces its value. Labels are removed by their key.
+
+When no snapshotID is given, all snapshots matching the host, tag, path and label filter criteria are modified.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLabel(cmd.Context(), labelOptions, globalOptions, args)
+	},
+}
+
+// LabelOptions bundles all options for the 'label' command.
+type LabelOptions struct {
+	restic.SnapshotFilter
+	SetLabels    []string
+	AddLabels    []string
+	RemoveLabels []string
+}
+
+var labelOptions LabelOptions
+
+func init() {
+	cmdRoot.AddCommand(cmdLabel)
+
+	labelFlags := cmdLabel.Flags()
+	labelFlags.StringArrayVar(&labelOptions.SetLabels, "set", nil, "`label` in the format key=value which will replace the existing labels, pass an empty string to remove all labels (can be given multiple times)")
+	labelFlags.StringArrayVar(&labelOptions.AddLabels, "add", nil, "`label` in the format key=value which will be added to the existing labels (can be given multiple times)")
+	labelFlags.StringArrayVar(&labelOptions.RemoveLabels, "remove", nil, "`key` of a label which will be removed from the existing labels (can be given multiple times)")
+	initMultiSnapshotFilter(labelFlags, &labelOptions.SnapshotFilter, true)
+}
+
+func changeLabels(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot, setLabels map[string]string, replace bool, addLabels map[string]string, removeLabels []string) (bool, error) {
+	var changed bool
+
+	if replace {
+		changed = len(sn.Labels) != len(setLabels)
+		for key, value := range setLabels {
+			if old, ok := sn.Labels[key]; !ok || old != value {
+				changed = true
+			}
+		}
+		sn.Labels = nil
+		sn.AddLabels(setLabels)
+	} else {
+		changed = sn.AddLabels(addLabels)
+		if sn.RemoveLabels(removeLabels) {
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := replaceSnapshot(ctx, repo, sn); err != nil {
+			return false, err
+		}
+	}
+	return changed, nil
+}
+
+func runLabel(ctx context.Context, opts LabelOptions, gopts GlobalOptions, args []string) error {
+	if len(opts.SetLabels) == 0 && len(opts.AddLabels) == 0 && len(opts.RemoveLabels) == 0 {
+		return errors.Fatal("nothing to do!")
+	}
+	if len(opts.SetLabels) != 0 && (len(opts.AddLabels) != 0 || len(opts.RemoveLabels) != 0) {
+		return errors.Fatal("--set and --add/--remove cannot be given at the same time")
+	}
+
+	// Setting the labels to an empty string really means no labels.
+	setLabels := opts.SetLabels
+	if len(setLabels) == 1 && setLabels[0] == "" {
+		setLabels = nil
+	}
+	set, err := restic.ParseLabels(setLabels)
+	if err != nil {
+		return errors.Fatalf("invalid --set: %v", err)
+	}
+	add, err := restic.ParseLabels(opts.AddLabels)
+	if err != nil {
+		return errors.Fatalf("invalid --add: %v", err)
+	}
+
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	if !gopts.NoLock {
+		Verbosef("create exclusive lock for repository\n")
+		var lock *restic.Lock
+		lock, ctx, err = lockRepoExclusive(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	changeCnt := 0
+	for sn := range FindFilteredSnapshots(ctx, repo, repo, &opts.SnapshotFilter, args) {
+		changed, err := changeLabels(ctx, repo, sn, set, len(opts.SetLabels) != 0, add, opts.RemoveLabels)
+		if err != nil {
+			Warnf("unable to modify the labels for snapshot ID %q, ignoring: %v\n", sn.ID(), err)
+			continue
+		}
+		if changed {
+			changeCnt++
+		}
+	}
+	if changeCnt == 0 {
+		Verbosef("no snapshots were modified\n")
+	} else {
+		Verbosef("modified labels on %v snapshots\n", changeCnt)
+	}
+	return nil
+}
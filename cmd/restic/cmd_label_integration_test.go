@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testRunLabel(t testing.TB, opts LabelOptions, gopts GlobalOptions) {
+	rtest.OK(t, runLabel(context.TODO(), opts, gopts, []string{}))
+}
+
+func testRunSnapshotsFiltered(t testing.TB, gopts GlobalOptions, filter restic.SnapshotFilter) []Snapshot {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+
+		opts := SnapshotOptions{SnapshotFilter: filter}
+		return runSnapshots(context.TODO(), opts, gopts, []string{})
+	})
+	rtest.OK(t, err)
+
+	snapshots := []Snapshot{}
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &snapshots))
+	return snapshots
+}
+
+func TestLabel(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{Labels: []string{"app=db", "env=prod"}}, env.gopts)
+	testRunCheck(t, env.gopts)
+	newest, _ := testRunSnapshots(t, env.gopts)
+	if newest == nil {
+		t.Fatal("expected a new backup, got nil")
+	}
+	rtest.Equals(t, map[string]string{"app": "db", "env": "prod"}, newest.Labels)
+	originalID := *newest.ID
+
+	testRunLabel(t, LabelOptions{AddLabels: []string{"env=test"}, RemoveLabels: []string{"app"}}, env.gopts)
+	testRunCheck(t, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	if newest == nil {
+		t.Fatal("expected a backup, got nil")
+	}
+	rtest.Equals(t, map[string]string{"env": "test"}, newest.Labels)
+	rtest.Assert(t, newest.Original != nil && *newest.Original == originalID,
+		"expected original ID to be set to the first snapshot id")
+
+	testRunLabel(t, LabelOptions{SetLabels: []string{"team=ops"}}, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	rtest.Equals(t, map[string]string{"team": "ops"}, newest.Labels)
+
+	rtest.Equals(t, 1, len(testRunSnapshotsFiltered(t, env.gopts, restic.SnapshotFilter{Labels: []string{"team=ops"}})))
+	rtest.Equals(t, 1, len(testRunSnapshotsFiltered(t, env.gopts, restic.SnapshotFilter{Labels: []string{"team"}})))
+	rtest.Equals(t, 0, len(testRunSnapshotsFiltered(t, env.gopts, restic.SnapshotFilter{Labels: []string{"team=dev"}})))
+
+	testRunLabel(t, LabelOptions{SetLabels: []string{""}}, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	rtest.Assert(t, len(newest.Labels) == 0, "expected no labels, got %v", newest.Labels)
+	testRunCheck(t, env.gopts)
+}
+
+func TestBackupParentByLabel(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	groupBy := restic.SnapshotGroupByOptions{Path: true, Labels: []string{"app"}}
+
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{Labels: []string{"app=db"}}, env.gopts)
+	labeled, _ := testRunSnapshots(t, env.gopts)
+
+	findParent := func(opts BackupOptions) *restic.Snapshot {
+		repo, err := OpenRepository(context.TODO(), env.gopts)
+		rtest.OK(t, err)
+		opts.GroupBy = groupBy
+		parent, err := findParentSnapshot(context.TODO(), repo, opts, []string{env.testdata}, time.Now())
+		rtest.OK(t, err)
+		return parent
+	}
+
+	// a backup without the label must not use the labeled snapshot as parent
+	parent := findParent(BackupOptions{})
+	rtest.Assert(t, parent == nil, "unexpected parent snapshot %v", parent)
+
+	parent = findParent(BackupOptions{Labels: []string{"app=db"}})
+	rtest.Assert(t, parent != nil && parent.ID().Equal(*labeled.ID), "wrong parent snapshot %v", parent)
+}
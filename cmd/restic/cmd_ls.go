@@ -123,6 +123,7 @@ func lsNodeJSON(enc *json.Encoder, path string, node *restic.Node) error {
 		AccessTime  time.Time   `json:"atime,omitempty"`
 		ChangeTime  time.Time   `json:"ctime,omitempty"`
 		Inode       uint64      `json:"inode,omitempty"`
+		FileHash    string      `json:"file_hash,omitempty"`
 		MessageType string      `json:"message_type"` // "node"
 		StructType  string      `json:"struct_type"`  // "node", deprecated
 
@@ -140,6 +141,7 @@ func lsNodeJSON(enc *json.Encoder, path string, node *restic.Node) error {
 		AccessTime:  node.AccessTime,
 		ChangeTime:  node.ChangeTime,
 		Inode:       node.Inode,
+		FileHash:    node.FileHash,
 		MessageType: "node",
 		StructType:  "node",
 	}
@@ -53,6 +53,7 @@ For path templates, you can use the following patterns which will be replaced:
     %u by username
     %h by hostname
     %t by tags
+    %l{key} by the value of the label key
     %T by timestamp as specified by --time-template
 
 The default path templates are:
@@ -51,8 +51,12 @@ type RestoreOptions struct {
 	InsensitiveInclude []string
 	Target             string
 	restic.SnapshotFilter
-	Sparse bool
-	Verify bool
+	xattrFilterOptions
+	Sparse    bool
+	Verify    bool
+	Overwrite restorer.OverwriteBehavior
+	Delete    bool
+	DryRun    bool
 }
 
 var restoreOptions RestoreOptions
@@ -68,8 +72,12 @@ func init() {
 	flags.StringVarP(&restoreOptions.Target, "target", "t", "", "directory to extract data to")
 
 	initSingleSnapshotFilter(flags, &restoreOptions.SnapshotFilter)
+	initXattrFilterOptions(flags, &restoreOptions.xattrFilterOptions, "restore")
 	flags.BoolVar(&restoreOptions.Sparse, "sparse", false, "restore files as sparse")
 	flags.BoolVar(&restoreOptions.Verify, "verify", false, "verify restored files content")
+	flags.Var(&restoreOptions.Overwrite, "overwrite", "overwrite `behavior` for existing files, one of always, if-changed, if-newer or never")
+	flags.BoolVar(&restoreOptions.Delete, "delete", false, "delete files from target directory if they do not exist in snapshot")
+	flags.BoolVar(&restoreOptions.DryRun, "dry-run", false, "do not write any data, just show what would be done")
 }
 
 func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
@@ -100,6 +108,10 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		}
 	}
 
+	if err := opts.xattrFilterOptions.Check(); err != nil {
+		return err
+	}
+
 	for i, str := range opts.InsensitiveExclude {
 		opts.InsensitiveExclude[i] = strings.ToLower(str)
 	}
@@ -123,6 +135,10 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		return errors.Fatal("exclude and include patterns are mutually exclusive")
 	}
 
+	if opts.DryRun && opts.Verify {
+		return errors.Fatal("--dry-run and --verify are mutually exclusive")
+	}
+
 	snapshotIDString := args[0]
 
 	debug.Log("restore %v to %v", snapshotIDString, opts.Target)
@@ -164,9 +180,9 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	msg := ui.NewMessage(term, gopts.verbosity)
 	var printer restoreui.ProgressPrinter
 	if gopts.JSON {
-		printer = restoreui.NewJSONProgress(term)
+		printer = restoreui.NewJSONProgress(term, gopts.verbosity, opts.DryRun)
 	} else {
-		printer = restoreui.NewTextProgress(term)
+		printer = restoreui.NewTextProgress(term, gopts.verbosity, opts.DryRun)
 	}
 
 	progress := restoreui.NewProgress(printer, calculateProgressInterval(!gopts.Quiet, gopts.JSON))
@@ -181,6 +197,10 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	res.Warn = func(message string) {
 		msg.E("Warning: %s\n", message)
 	}
+	res.SelectXattr = opts.xattrFilterOptions.SelectFunc()
+	res.Overwrite = opts.Overwrite
+	res.Delete = opts.Delete
+	res.DryRun = opts.DryRun
 
 	excludePatterns := filter.ParsePatterns(opts.Exclude)
 	insensitiveExcludePatterns := filter.ParsePatterns(opts.InsensitiveExclude)
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui"
 	"github.com/restic/restic/internal/ui/table"
 	"github.com/spf13/cobra"
 )
@@ -54,7 +55,7 @@ func init() {
 		panic(err)
 	}
 	f.IntVar(&snapshotOptions.Latest, "latest", 0, "only show the last `n` snapshots for each host and path")
-	f.VarP(&snapshotOptions.GroupBy, "group-by", "g", "`group` snapshots by host, paths and/or tags, separated by comma")
+	f.VarP(&snapshotOptions.GroupBy, "group-by", "g", "`group` snapshots by host, paths, tags and/or label:key, separated by comma")
 }
 
 func runSnapshots(ctx context.Context, opts SnapshotOptions, gopts GlobalOptions, args []string) error {
@@ -171,7 +172,11 @@ func PrintSnapshots(stdout io.Writer, list restic.Snapshots, reasons []restic.Ke
 
 	// Determine the max widths for host and tag.
 	maxHost, maxTag := 10, 6
+	hasDescription := false
 	for _, sn := range list {
+		if sn.Description != "" {
+			hasDescription = true
+		}
 		if len(sn.Hostname) > maxHost {
 			maxHost = len(sn.Hostname)
 		}
@@ -197,16 +202,22 @@ func PrintSnapshots(stdout io.Writer, list restic.Snapshots, reasons []restic.Ke
 		if len(reasons) > 0 {
 			tab.AddColumn("Reasons", `{{ join .Reasons "\n" }}`)
 		}
+		if hasDescription {
+			tab.AddColumn("Description", `{{ join .Description "\n" }}`)
+		}
 		tab.AddColumn("Paths", `{{ join .Paths "\n" }}`)
+		tab.AddColumn("Size", `{{ .Size }}`)
 	}
 
 	type snapshot struct {
-		ID        string
-		Timestamp string
-		Hostname  string
-		Tags      []string
-		Reasons   []string
-		Paths     []string
+		ID          string
+		Timestamp   string
+		Hostname    string
+		Tags        []string
+		Reasons     []string
+		Description []string
+		Paths       []string
+		Size        string
 	}
 
 	var multiline bool
@@ -219,12 +230,20 @@ func PrintSnapshots(stdout io.Writer, list restic.Snapshots, reasons []restic.Ke
 			Paths:     sn.Paths,
 		}
 
+		if sn.Summary != nil {
+			data.Size = ui.FormatBytes(sn.Summary.TotalBytesProcessed)
+		}
+
 		if len(reasons) > 0 {
 			id := sn.ID()
 			data.Reasons = keepReasons[*id].Matches
 		}
 
-		if len(sn.Paths) > 1 && !compact {
+		if sn.Description != "" {
+			data.Description = strings.Split(sn.Description, "\n")
+		}
+
+		if (len(sn.Paths) > 1 || len(data.Description) > 1) && !compact {
 			multiline = true
 		}
 
@@ -266,7 +285,7 @@ func PrintSnapshotGroupHeader(stdout io.Writer, groupKeyJSON string) error {
 		return err
 	}
 
-	if key.Hostname == "" && key.Tags == nil && key.Paths == nil {
+	if key.Hostname == "" && key.Tags == nil && key.Paths == nil && key.Labels == nil {
 		return nil
 	}
 
@@ -282,6 +301,14 @@ func PrintSnapshotGroupHeader(stdout io.Writer, groupKeyJSON string) error {
 	if key.Paths != nil {
 		infoStrings = append(infoStrings, "paths ["+strings.Join(key.Paths, ", ")+"]")
 	}
+	if key.Labels != nil {
+		labels := make([]string, 0, len(key.Labels))
+		for k, v := range key.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		infoStrings = append(infoStrings, "labels ["+strings.Join(labels, ", ")+"]")
+	}
 	if infoStrings != nil {
 		fmt.Fprintf(stdout, " for (%s)", strings.Join(infoStrings, ", "))
 	}
@@ -72,28 +72,36 @@ func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Sna
 	}
 
 	if changed {
-		// Retain the original snapshot id over all tag changes.
-		if sn.Original == nil {
-			sn.Original = sn.ID()
-		}
-
-		// Save the new snapshot.
-		id, err := restic.SaveSnapshot(ctx, repo, sn)
-		if err != nil {
+		if err := replaceSnapshot(ctx, repo, sn); err != nil {
 			return false, err
 		}
+	}
+	return changed, nil
+}
 
-		debug.Log("new snapshot saved as %v", id)
+// replaceSnapshot saves the modified snapshot sn and removes the old version.
+func replaceSnapshot(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot) error {
+	// Retain the original snapshot id over all changes.
+	if sn.Original == nil {
+		sn.Original = sn.ID()
+	}
 
-		// Remove the old snapshot.
-		h := backend.Handle{Type: restic.SnapshotFile, Name: sn.ID().String()}
-		if err = repo.Backend().Remove(ctx, h); err != nil {
-			return false, err
-		}
+	// Save the new snapshot.
+	id, err := restic.SaveSnapshot(ctx, repo, sn)
+	if err != nil {
+		return err
+	}
+
+	debug.Log("new snapshot saved as %v", id)
 
-		debug.Log("old snapshot %v removed", sn.ID())
+	// Remove the old snapshot.
+	h := backend.Handle{Type: restic.SnapshotFile, Name: sn.ID().String()}
+	if err = repo.Backend().Remove(ctx, h); err != nil {
+		return err
 	}
-	return changed, nil
+
+	debug.Log("old snapshot %v removed", sn.ID())
+	return nil
 }
 
 func runTag(ctx context.Context, opts TagOptions, gopts GlobalOptions, args []string) error {
@@ -6,15 +6,20 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/filter"
 	"github.com/restic/restic/internal/fs"
 	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
 	"github.com/restic/restic/internal/textfile"
 	"github.com/restic/restic/internal/ui"
 	"github.com/spf13/pflag"
@@ -204,13 +209,232 @@ func isDirExcludedByFile(dir, tagFilename, header string) bool {
 	return true
 }
 
+// ignoreRule is a single pattern read from a per-directory ignore file.
+type ignoreRule struct {
+	pattern filter.Pattern
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreFileCache stores the rules of all ignore files which have been read so
+// far, indexed by the directory containing the ignore file. A nil value means
+// that the directory does not contain an ignore file.
+type ignoreFileCache struct {
+	filename   string
+	filesystem fs.FS
+	// targets contains the absolute paths of the backup targets
+	targets []string
+	m       map[string][]ignoreRule
+	mtx     sync.Mutex
+}
+
+// rules returns the rules of the ignore file in dir, the file is only read on
+// the first call for each directory.
+func (c *ignoreFileCache) rules(dir string) []ignoreRule {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	rules, ok := c.m[dir]
+	if ok {
+		return rules
+	}
+
+	rules, err := readIgnoreFile(c.filesystem, filepath.Join(dir, c.filename))
+	if err != nil && !os.IsNotExist(err) {
+		Warnf("could not read ignore file: %v\n", err)
+	}
+
+	if c.m == nil {
+		c.m = make(map[string][]ignoreRule)
+	}
+	c.m[dir] = rules
+	return rules
+}
+
+// rejectByIgnoreFile returns a RejectByNameFunc which rejects files according
+// to the rules found in files named filename in the directories containing
+// the file or one of its parent directories. The rules follow the semantics
+// of gitignore: patterns without a slash match the name of files at any depth
+// below the directory of the ignore file, other patterns are anchored at
+// that directory. A trailing slash restricts a pattern to directories, and a
+// leading exclamation mark re-includes files excluded by an earlier pattern.
+// Rules in deeper directories take precedence over those in parent
+// directories. Only the directories below the backup target containing the
+// file are searched for ignore files, which are read from filesystem.
+func rejectByIgnoreFile(filename string, targets []string, filesystem fs.FS) (RejectByNameFunc, error) {
+	if filename == "" {
+		return nil, errors.New("name for ignore file is empty")
+	}
+	if strings.ContainsAny(filename, `/\`) {
+		return nil, fmt.Errorf("name for ignore file %q must not contain a path separator", filename)
+	}
+	debug.Log("using %q as ignore file", filename)
+
+	c := &ignoreFileCache{filename: filename, filesystem: filesystem}
+	for _, target := range targets {
+		abstarget, err := filesystem.Abs(target)
+		if err != nil {
+			return nil, err
+		}
+		c.targets = append(c.targets, abstarget)
+	}
+
+	return func(item string) bool {
+		return isExcludedByIgnoreFile(item, c)
+	}, nil
+}
+
+// isExcludedByIgnoreFile returns true if the rules of the ignore files in the
+// parent directories of item exclude it. The parent directories are searched
+// up to the innermost backup target which contains item.
+func isExcludedByIgnoreFile(item string, c *ignoreFileCache) bool {
+	absitem, err := c.filesystem.Abs(item)
+	if err != nil {
+		debug.Log("unable to get absolute path of %v: %v", item, err)
+		return false
+	}
+	item = absitem
+
+	target := ""
+	for _, t := range c.targets {
+		if fs.HasPathPrefix(t, item) && len(t) > len(target) {
+			target = t
+		}
+	}
+	if target == "" || target == item {
+		// ignore files outside of the backup targets do not apply
+		return false
+	}
+
+	// only check whether item is a directory if a rule requires it
+	var isDir *bool
+
+	for dir := filepath.Dir(item); ; dir = filepath.Dir(dir) {
+		rules := c.rules(dir)
+		rel := "/" + filepath.ToSlash(strings.TrimPrefix(strings.TrimPrefix(item, dir), string(filepath.Separator)))
+
+		// the last matching rule wins
+		for i := len(rules) - 1; i >= 0; i-- {
+			rule := rules[i]
+			if !rule.matches(rel) {
+				continue
+			}
+
+			if rule.dirOnly {
+				if isDir == nil {
+					fi, err := c.filesystem.Lstat(item)
+					v := err == nil && fi.IsDir()
+					isDir = &v
+				}
+				if !*isDir {
+					continue
+				}
+			}
+
+			if !rule.negate {
+				debug.Log("path %q excluded by ignore file in %v", item, dir)
+			}
+			return !rule.negate
+		}
+
+		if dir == target {
+			return false
+		}
+	}
+}
+
+// matches returns true if the rule matches the path rel, which is relative
+// to the directory containing the ignore file and starts with a slash.
+func (r ignoreRule) matches(rel string) bool {
+	matched, err := filter.List([]filter.Pattern{r.pattern}, rel)
+	if err != nil {
+		// invalid patterns have been removed while reading the ignore file
+		debug.Log("error matching %q: %v", rel, err)
+		return false
+	}
+	return matched
+}
+
+// readIgnoreFile parses the ignore file at filename in filesystem. Empty lines
+// and comments starting with a hash sign are skipped.
+func readIgnoreFile(filesystem fs.FS, filename string) ([]ignoreRule, error) {
+	f, err := filesystem.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil {
+		return nil, err
+	}
+	data, err = textfile.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := trimIgnoreLine(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		// a slash at the beginning or in the middle anchors the pattern
+		if strings.Contains(line, "/") {
+			line = "/" + strings.TrimLeft(line, "/")
+		}
+		if line == "" || line == "/" {
+			continue
+		}
+		// "foo/**" matches everything inside foo, but not foo itself
+		if strings.HasSuffix(line, "/**") {
+			line = strings.TrimSuffix(line, "**") + "*/**"
+		}
+
+		if err := filter.ValidatePatterns([]string{line}); err != nil {
+			Warnf("ignore file %v: %v\n", filename, err)
+			continue
+		}
+
+		rule.pattern = filter.ParsePatterns([]string{line})[0]
+		rules = append(rules, rule)
+	}
+
+	return rules, scanner.Err()
+}
+
+// trimIgnoreLine removes trailing white space from line unless it is escaped
+// with a backslash.
+func trimIgnoreLine(line string) string {
+	line = strings.TrimRight(line, "\r")
+	for len(line) > 0 && (line[len(line)-1] == ' ' || line[len(line)-1] == '\t') {
+		if len(line) > 1 && line[len(line)-2] == '\\' {
+			// keep the escaped white space character, but drop the backslash
+			return line[:len(line)-2] + line[len(line)-1:]
+		}
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
 // DeviceMap is used to track allowed source devices for backup. This is used to
 // check for crossing mount points during backup (for --one-file-system). It
 // maps the name of a source path to its device ID.
 type DeviceMap map[string]uint64
 
-// NewDeviceMap creates a new device map from the list of source paths.
-func NewDeviceMap(allowedSourcePaths []string) (DeviceMap, error) {
+// NewDeviceMap creates a new device map from the list of source paths. The
+// device IDs are read from filesystem.
+func NewDeviceMap(allowedSourcePaths []string, filesystem fs.FS) (DeviceMap, error) {
 	deviceMap := make(map[string]uint64)
 
 	for _, item := range allowedSourcePaths {
@@ -219,7 +443,7 @@ func NewDeviceMap(allowedSourcePaths []string) (DeviceMap, error) {
 			return nil, err
 		}
 
-		fi, err := fs.Lstat(item)
+		fi, err := filesystem.Lstat(item)
 		if err != nil {
 			return nil, err
 		}
@@ -272,8 +496,8 @@ func (m DeviceMap) IsAllowed(item string, deviceID uint64) (bool, error) {
 
 // rejectByDevice returns a RejectFunc that rejects files which are on a
 // different file systems than the files/dirs in samples.
-func rejectByDevice(samples []string) (RejectFunc, error) {
-	deviceMap, err := NewDeviceMap(samples)
+func rejectByDevice(samples []string, filesystem fs.FS) (RejectFunc, error) {
+	deviceMap, err := NewDeviceMap(samples, filesystem)
 	if err != nil {
 		return nil, err
 	}
@@ -308,7 +532,7 @@ func rejectByDevice(samples []string) (RejectFunc, error) {
 		// directory would be included.
 		parentDir := filepath.Dir(filepath.Clean(item))
 
-		parentFI, err := fs.Lstat(parentDir)
+		parentFI, err := filesystem.Lstat(parentDir)
 		if err != nil {
 			debug.Log("item %v: error running lstat() on parent directory: %v", item, err)
 			// if in doubt, reject
@@ -339,6 +563,76 @@ func rejectByDevice(samples []string) (RejectFunc, error) {
 	}, nil
 }
 
+// MountTable maps the path of a mount point to the type of the mounted file
+// system. For paths with several stacked mounts the topmost mount is recorded.
+type MountTable map[string]string
+
+// NewMountTable creates a mount table from the list of mounted file systems.
+func NewMountTable(mounts []fs.Mount) MountTable {
+	m := make(MountTable, len(mounts))
+	for _, mount := range mounts {
+		// later entries hide earlier mounts at the same mount point
+		m[mount.MountPoint] = mount.FSType
+	}
+	return m
+}
+
+// FSType returns the type of the file system the path item resides on.
+func (m MountTable) FSType(item string, filesystem fs.FS) (string, bool) {
+	for dir := item; ; dir = filesystem.Dir(dir) {
+		if fsType, ok := m[dir]; ok {
+			return fsType, true
+		}
+
+		if filesystem.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// rejectByFSType returns a RejectFunc that rejects files which reside on a
+// file system of one of the given types. Mount points of such file systems
+// are kept as empty directories, like with --one-file-system.
+func rejectByFSType(fsTypes []string, filesystem fs.FS) (RejectFunc, error) {
+	mounts, err := fs.ReadMounts()
+	if err != nil {
+		return nil, errors.Fatalf("--exclude-fs-type: unable to read mount table: %v", err)
+	}
+
+	return rejectByMountTable(NewMountTable(mounts), fsTypes, filesystem), nil
+}
+
+func rejectByMountTable(mounts MountTable, fsTypes []string, filesystem fs.FS) RejectFunc {
+	excluded := make(map[string]struct{}, len(fsTypes))
+	for _, t := range fsTypes {
+		excluded[strings.TrimSpace(t)] = struct{}{}
+	}
+	debug.Log("excluded file system types: %v\n", fsTypes)
+
+	return func(item string, fi os.FileInfo) bool {
+		item = filesystem.Clean(item)
+		// an item resides on the file system its parent directory is located
+		// on, unless it is a mount point itself
+		parentDir := filesystem.Dir(item)
+		if parentDir == item {
+			// never reject the root directory
+			return false
+		}
+
+		fsType, ok := mounts.FSType(parentDir, filesystem)
+		if !ok {
+			return false
+		}
+
+		if _, ok := excluded[fsType]; ok {
+			debug.Log("item %v is on a file system of type %v", item, fsType)
+			return true
+		}
+
+		return false
+	}
+}
+
 // rejectResticCache returns a RejectByNameFunc that rejects the restic cache
 // directory (if set).
 func rejectResticCache(repo *repository.Repository) (RejectByNameFunc, error) {
@@ -385,6 +679,176 @@ func rejectBySize(maxSizeStr string) (RejectFunc, error) {
 	}, nil
 }
 
+// rejectOlderThan returns a RejectFunc which rejects files which have last been
+// modified more than d before now. Directories are never rejected.
+func rejectOlderThan(d restic.Duration, now time.Time) RejectFunc {
+	limit := now.AddDate(-d.Years, -d.Months, -d.Days).Add(time.Hour * time.Duration(-d.Hours))
+
+	return func(item string, fi os.FileInfo) bool {
+		if fi.IsDir() {
+			return false
+		}
+
+		if fi.ModTime().Before(limit) {
+			debug.Log("file %s is older than %v: %v", item, d, fi.ModTime())
+			return true
+		}
+
+		return false
+	}
+}
+
+// rejectNewerThan returns a RejectFunc which rejects files which have been
+// modified within d before now. Directories are never rejected.
+func rejectNewerThan(d restic.Duration, now time.Time) RejectFunc {
+	limit := now.AddDate(-d.Years, -d.Months, -d.Days).Add(time.Hour * time.Duration(-d.Hours))
+
+	return func(item string, fi os.FileInfo) bool {
+		if fi.IsDir() {
+			return false
+		}
+
+		if fi.ModTime().After(limit) {
+			debug.Log("file %s is newer than %v: %v", item, d, fi.ModTime())
+			return true
+		}
+
+		return false
+	}
+}
+
+// rejectByOwner returns a RejectFunc which rejects files and directories owned
+// by one of the given users, which are specified by name or numeric ID.
+func rejectByOwner(owners []string) (RejectFunc, error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.Fatal("--exclude-owner is not supported on Windows")
+	}
+
+	uids := make(map[uint32]struct{}, len(owners))
+	for _, owner := range owners {
+		uid, err := lookupUID(owner)
+		if err != nil {
+			return nil, errors.Fatalf("--exclude-owner: %v", err)
+		}
+		uids[uid] = struct{}{}
+	}
+
+	return func(item string, fi os.FileInfo) bool {
+		var uid uint32
+		if info, ok := fi.Sys().(*fs.ArchiveEntryInfo); ok {
+			uid = info.UID
+		} else {
+			var err error
+			uid, err = fs.UserID(fi)
+			if err != nil {
+				// e.g. the virtual files of --command-file
+				debug.Log("unable to determine owner of %v: %v", item, err)
+				return false
+			}
+		}
+
+		if _, ok := uids[uid]; ok {
+			debug.Log("item %s is owned by excluded user %d", item, uid)
+			return true
+		}
+
+		return false
+	}, nil
+}
+
+// lookupUID returns the numeric user ID for owner, which is either a user
+// name or a user ID.
+func lookupUID(owner string) (uint32, error) {
+	if uid, err := strconv.ParseUint(owner, 10, 32); err == nil {
+		return uint32(uid), nil
+	}
+
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, err
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, errors.Errorf("invalid UID %q for user %q", u.Uid, owner)
+	}
+	return uint32(uid), nil
+}
+
+// excludeFileTypes maps the names accepted by --exclude-type to a function
+// which checks whether a file mode is of that type.
+var excludeFileTypes = map[string]func(os.FileMode) bool{
+	"symlink": func(m os.FileMode) bool { return m&os.ModeSymlink != 0 },
+	"fifo":    func(m os.FileMode) bool { return m&os.ModeNamedPipe != 0 },
+	"socket":  func(m os.FileMode) bool { return m&os.ModeSocket != 0 },
+	"device":  func(m os.FileMode) bool { return m&os.ModeDevice != 0 },
+	// character devices have both ModeDevice and ModeCharDevice set
+	"blockdev": func(m os.FileMode) bool { return m&os.ModeDevice != 0 && m&os.ModeCharDevice == 0 },
+	"chardev":  func(m os.FileMode) bool { return m&os.ModeCharDevice != 0 },
+}
+
+// rejectByType returns a RejectFunc which rejects files of the given types.
+func rejectByType(types []string) (RejectFunc, error) {
+	var checks []func(os.FileMode) bool
+	for _, t := range types {
+		check, ok := excludeFileTypes[strings.TrimSpace(t)]
+		if !ok {
+			return nil, errors.Fatalf("--exclude-type: unknown file type %q", t)
+		}
+		checks = append(checks, check)
+	}
+
+	return func(item string, fi os.FileInfo) bool {
+		for _, check := range checks {
+			if check(fi.Mode()) {
+				debug.Log("item %s has excluded type %v", item, fi.Mode().Type())
+				return true
+			}
+		}
+
+		return false
+	}, nil
+}
+
+// rejectNoDump returns a RejectFunc which rejects files and directories which
+// have the nodump inode flag set. The flags are read from filesystem.
+func rejectNoDump(filesystem fs.FS) (RejectFunc, error) {
+	if runtime.GOOS != "linux" {
+		return nil, errors.Fatal("--exclude-nodump is only supported on Linux")
+	}
+
+	return func(item string, fi os.FileInfo) bool {
+		// inode flags can only be read from files and directories on the
+		// local file system
+		if _, ok := fi.Sys().(*fs.ArchiveEntryInfo); ok {
+			return false
+		}
+		if !fi.Mode().IsRegular() && !fi.IsDir() {
+			return false
+		}
+
+		// do not follow the item if it was replaced by a symlink in the meantime
+		f, err := filesystem.OpenFile(item, fs.O_RDONLY|fs.O_NOFOLLOW, 0)
+		if err != nil {
+			debug.Log("unable to open %v: %v", item, err)
+			return false
+		}
+		flags, err := fs.GetFileInodeFlags(f)
+		_ = f.Close()
+		if err != nil {
+			debug.Log("unable to read inode flags of %v: %v", item, err)
+			return false
+		}
+
+		if flags&fs.InodeFlagNoDump != 0 {
+			debug.Log("item %s has the nodump flag set", item)
+			return true
+		}
+
+		return false
+	}, nil
+}
+
 // readExcludePatternsFromFiles reads all exclude files and returns the list of
 // exclude patterns. For each line, leading and trailing white space is removed
 // and comment lines are ignored. For each remaining pattern, environment
@@ -3,8 +3,13 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/restic"
 	"github.com/restic/restic/internal/test"
 )
 
@@ -267,6 +272,81 @@ func TestIsExcludedByFileSize(t *testing.T) {
 	}
 }
 
+func TestIsExcludedByIgnoreFile(t *testing.T) {
+	tempDir := test.TempDir(t)
+
+	files := []struct {
+		path string
+		data string
+		incl bool
+	}{
+		{".ignore", "# comment\n*.o\n/build\ndoc/*.html\ncache/\n!keep.o\nnested/**\n", true},
+		{"main.c", "", true},
+		{"main.o", "", false},
+		{"keep.o", "", true},
+		{"build/out", "", false},
+		{"doc/index.html", "", false},
+		{"doc/sub/index.html", "", true},
+		{"cache", "", true},
+		{"nested/file", "", false},
+		{"sub/build/out", "", true},
+		{"sub/lib.o", "", false},
+		{"sub/cache/data", "", false},
+
+		// rules in subdirectories take precedence
+		{"other/.ignore", "!*.o\n*.tmp\n", true},
+		{"other/lib.o", "", true},
+		{"other/x.tmp", "", false},
+		{"main.tmp", "", true},
+	}
+	var errs []error
+	for _, f := range files {
+		// create directories first, then the file
+		p := filepath.Join(tempDir, filepath.FromSlash(f.path))
+		errs = append(errs, os.MkdirAll(filepath.Dir(p), 0700))
+		errs = append(errs, os.WriteFile(p, []byte(f.data), 0600))
+	}
+	test.OKs(t, errs) // see if anything went wrong during the creation
+
+	ignoreExclude, err := rejectByIgnoreFile(".ignore", []string{tempDir}, fs.Local{})
+	test.OK(t, err)
+
+	m := make(map[string]bool)
+	walk := func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		excluded := ignoreExclude(p)
+		// the log message helps debugging in case the test fails
+		t.Logf("%q: %v", p, excluded)
+		m[p] = !excluded
+		if excluded && fi.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	// walk through the temporary file and check the error
+	test.OK(t, filepath.Walk(tempDir, walk))
+
+	// compare whether the walk gave the expected values for the test cases
+	for _, f := range files {
+		p := filepath.Join(tempDir, filepath.FromSlash(f.path))
+		if m[p] != f.incl {
+			t.Errorf("inclusion status of %s is wrong: want %v, got %v", f.path, f.incl, m[p])
+		}
+	}
+
+	// ignore files above the backup target do not apply
+	subExclude, err := rejectByIgnoreFile(".ignore", []string{filepath.Join(tempDir, "sub")}, fs.Local{})
+	test.OK(t, err)
+	test.Assert(t, !subExclude(filepath.Join(tempDir, "sub", "lib.o")), "ignore file above the target was applied")
+	test.Assert(t, !subExclude(filepath.Join(tempDir, "sub")), "target was excluded")
+	test.Assert(t, !subExclude(filepath.Join(tempDir, "main.o")), "ignore file outside of the target was applied")
+
+	_, err = rejectByIgnoreFile("", nil, fs.Local{})
+	test.Assert(t, err != nil, "missing error for empty ignore file name")
+}
+
 func TestDeviceMap(t *testing.T) {
 	deviceMap := DeviceMap{
 		filepath.FromSlash("/"):          1,
@@ -310,3 +390,165 @@ func TestDeviceMap(t *testing.T) {
 		})
 	}
 }
+
+func TestRejectByFSType(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mount tables are not available on Windows")
+	}
+
+	mounts := NewMountTable([]fs.Mount{
+		{MountPoint: "/", FSType: "ext4"},
+		{MountPoint: "/proc", FSType: "proc"},
+		{MountPoint: "/tmp", FSType: "tmpfs"},
+		{MountPoint: "/tmp/disk", FSType: "ext4"},
+		{MountPoint: "/mnt/with space", FSType: "fuse.sshfs"},
+		{MountPoint: "/mnt/remote", FSType: "nfs"},
+		{MountPoint: "/mnt/remote", FSType: "ext4"},
+	})
+
+	reject := rejectByMountTable(mounts, []string{"tmpfs", "proc", "nfs", "fuse.sshfs"}, fs.Local{})
+
+	for _, tt := range []struct {
+		item   string
+		reject bool
+	}{
+		{"/", false},
+		{"/home/user/file", false},
+		// mount points are kept
+		{"/proc", false},
+		{"/tmp", false},
+		{"/proc/1/status", true},
+		{"/tmp/file", true},
+		{"/tmp/dir/file", true},
+		{"/tmp/disk", true},
+		{"/tmp/disk/file", false},
+		{"/mnt/with space/file", true},
+		// the topmost of several stacked mounts is used
+		{"/mnt/remote/file", false},
+	} {
+		t.Run(tt.item, func(t *testing.T) {
+			test.Equals(t, tt.reject, reject(tt.item, nil))
+		})
+	}
+}
+
+type testFileInfo struct {
+	os.FileInfo
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (fi testFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi testFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi testFileInfo) ModTime() time.Time { return fi.modTime }
+func (fi testFileInfo) Sys() interface{}   { return nil }
+
+func TestRejectByAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	olderThan := rejectOlderThan(restic.Duration{Years: 1}, now)
+	newerThan := rejectNewerThan(restic.Duration{Days: 2, Hours: 12}, now)
+
+	for _, tt := range []struct {
+		fi        testFileInfo
+		olderThan bool
+		newerThan bool
+	}{
+		{testFileInfo{modTime: now.AddDate(-2, 0, 0)}, true, false},
+		{testFileInfo{modTime: now.AddDate(0, -11, 0)}, false, false},
+		{testFileInfo{modTime: now.Add(-time.Hour)}, false, true},
+		{testFileInfo{modTime: now.AddDate(0, 0, -3)}, false, false},
+		// directories are never rejected
+		{testFileInfo{mode: os.ModeDir, modTime: now.AddDate(-2, 0, 0)}, false, false},
+		{testFileInfo{mode: os.ModeDir, modTime: now}, false, false},
+	} {
+		test.Equals(t, tt.olderThan, olderThan("item", tt.fi))
+		test.Equals(t, tt.newerThan, newerThan("item", tt.fi))
+	}
+}
+
+func TestRejectByType(t *testing.T) {
+	reject, err := rejectByType([]string{"socket", "fifo", "chardev"})
+	test.OK(t, err)
+
+	for _, tt := range []struct {
+		mode   os.FileMode
+		reject bool
+	}{
+		{0644, false},
+		{os.ModeDir | 0755, false},
+		{os.ModeSymlink | 0777, false},
+		{os.ModeSocket | 0755, true},
+		{os.ModeNamedPipe | 0644, true},
+		{os.ModeDevice | os.ModeCharDevice | 0644, true},
+		{os.ModeDevice | 0644, false},
+	} {
+		test.Equals(t, tt.reject, reject("item", testFileInfo{mode: tt.mode}))
+	}
+
+	reject, err = rejectByType([]string{"device"})
+	test.OK(t, err)
+	test.Assert(t, reject("item", testFileInfo{mode: os.ModeDevice}), "block device not rejected")
+	test.Assert(t, reject("item", testFileInfo{mode: os.ModeDevice | os.ModeCharDevice}), "character device not rejected")
+
+	_, err = rejectByType([]string{"invalid"})
+	test.Assert(t, err != nil, "missing error for invalid file type")
+}
+
+func TestRejectByOwner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("--exclude-owner is not supported on Windows")
+	}
+
+	tempDir := test.TempDir(t)
+	fi, err := os.Lstat(tempDir)
+	test.OK(t, err)
+
+	uid := strconv.Itoa(os.Getuid())
+	reject, err := rejectByOwner([]string{uid})
+	test.OK(t, err)
+	test.Assert(t, reject(tempDir, fi), "item owned by %v not rejected", uid)
+
+	reject, err = rejectByOwner([]string{strconv.Itoa(os.Getuid() + 1)})
+	test.OK(t, err)
+	test.Assert(t, !reject(tempDir, fi), "item owned by %v rejected", uid)
+
+	// items without owner, like the files of --command-file, are kept
+	reject, err = rejectByOwner([]string{"0", uid})
+	test.OK(t, err)
+	test.Assert(t, !reject("item", testFileInfo{mode: 0644}), "item without owner rejected")
+}
+
+func TestRejectNoDump(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		_, err := rejectNoDump(fs.Local{})
+		test.Assert(t, err != nil, "missing error for unsupported platform")
+		return
+	}
+
+	tempDir := test.TempDir(t)
+	nodump := filepath.Join(tempDir, "nodump")
+	other := filepath.Join(tempDir, "other")
+	test.OK(t, os.WriteFile(nodump, []byte("foo"), 0644))
+	test.OK(t, os.WriteFile(other, []byte("bar"), 0644))
+
+	test.OK(t, fs.SetInodeFlags(nodump, fs.InodeFlagNoDump))
+	if flags, err := fs.GetInodeFlags(nodump); err != nil || flags&fs.InodeFlagNoDump == 0 {
+		t.Skipf("file system does not support inode flags: %v", err)
+	}
+
+	reject, err := rejectNoDump(fs.Local{})
+	test.OK(t, err)
+
+	for _, tt := range []struct {
+		item   string
+		reject bool
+	}{
+		{nodump, true},
+		{other, false},
+		{tempDir, false},
+	} {
+		fi, err := os.Lstat(tt.item)
+		test.OK(t, err)
+		test.Equals(t, tt.reject, reject(tt.item, fi))
+	}
+}
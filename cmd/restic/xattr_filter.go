@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/filter"
+	"github.com/restic/restic/internal/restic"
+
+	"github.com/spf13/pflag"
+)
+
+// xattrFilterOptions bundles the options which select the extended attributes
+// to save or restore.
+type xattrFilterOptions struct {
+	ExcludeXattrs []string
+	IncludeXattrs []string
+}
+
+func initXattrFilterOptions(f *pflag.FlagSet, opts *xattrFilterOptions, action string) {
+	f.StringArrayVar(&opts.ExcludeXattrs, "exclude-xattr", nil, "do not "+action+" extended attributes whose name matches `pattern`, e.g. security.selinux (can be specified multiple times)")
+	f.StringArrayVar(&opts.IncludeXattrs, "include-xattr", nil, "only "+action+" extended attributes whose name matches `pattern`, e.g. user.* (can be specified multiple times)")
+}
+
+// Empty returns true if no extended attribute filter is configured.
+func (opts *xattrFilterOptions) Empty() bool {
+	return len(opts.ExcludeXattrs) == 0 && len(opts.IncludeXattrs) == 0
+}
+
+// Check validates the patterns.
+func (opts *xattrFilterOptions) Check() error {
+	if err := filter.ValidatePatterns(opts.ExcludeXattrs); err != nil {
+		return errors.Fatalf("--exclude-xattr: %s", err)
+	}
+	if err := filter.ValidatePatterns(opts.IncludeXattrs); err != nil {
+		return errors.Fatalf("--include-xattr: %s", err)
+	}
+	return nil
+}
+
+// SelectFunc returns a function which selects an extended attribute if it
+// matches one of the include patterns, if any, and none of the exclude
+// patterns. If no patterns are configured, nil is returned.
+func (opts *xattrFilterOptions) SelectFunc() restic.XattrSelectFunc {
+	if opts.Empty() {
+		return nil
+	}
+
+	includes := filter.ParsePatterns(opts.IncludeXattrs)
+	excludes := filter.ParsePatterns(opts.ExcludeXattrs)
+
+	return func(name string) bool {
+		if len(includes) > 0 {
+			matched, err := filter.List(includes, name)
+			if err != nil {
+				debug.Log("error for include pattern: %v", err)
+			}
+			if !matched {
+				return false
+			}
+		}
+
+		matched, err := filter.List(excludes, name)
+		if err != nil {
+			debug.Log("error for exclude pattern: %v", err)
+		}
+		return !matched
+	}
+}
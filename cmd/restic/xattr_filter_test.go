@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestXattrFilterSelectFunc(t *testing.T) {
+	var tests = []struct {
+		opts     xattrFilterOptions
+		selected map[string]bool
+	}{
+		{
+			opts: xattrFilterOptions{ExcludeXattrs: []string{"security.selinux"}},
+			selected: map[string]bool{
+				"security.selinux": false,
+				"user.foo":         true,
+			},
+		},
+		{
+			opts: xattrFilterOptions{IncludeXattrs: []string{"user.*"}},
+			selected: map[string]bool{
+				"security.selinux": false,
+				"user.foo":         true,
+				"user.bar":         true,
+			},
+		},
+		{
+			opts: xattrFilterOptions{
+				IncludeXattrs: []string{"user.*"},
+				ExcludeXattrs: []string{"user.bar"},
+			},
+			selected: map[string]bool{
+				"security.selinux": false,
+				"user.foo":         true,
+				"user.bar":         false,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run("", func(t *testing.T) {
+			rtest.OK(t, tc.opts.Check())
+			selectXattr := tc.opts.SelectFunc()
+			for name, want := range tc.selected {
+				if got := selectXattr(name); got != want {
+					t.Errorf("wrong result for %v: want %v, got %v", name, want, got)
+				}
+			}
+		})
+	}
+
+	var empty xattrFilterOptions
+	rtest.Assert(t, empty.SelectFunc() == nil, "expected nil select function without patterns")
+}
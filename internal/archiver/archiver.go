@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/restic/restic/internal/debug"
@@ -50,6 +51,37 @@ func (s *ItemStats) Add(other ItemStats) {
 	s.TreeSizeInRepo += other.TreeSizeInRepo
 }
 
+// Summary contains statistics about a single backup run.
+type Summary struct {
+	BackupStart    time.Time
+	BackupEnd      time.Time
+	Files, Dirs    ChangeStats
+	ProcessedBytes uint64
+	ItemStats
+
+	// FilesChangedDuringBackup counts the files which were still modified
+	// while they were read, see Archiver.RetryChangedFiles.
+	FilesChangedDuringBackup uint
+}
+
+// ChangeStats counts new, changed and unchanged items.
+type ChangeStats struct {
+	New       uint
+	Changed   uint
+	Unchanged uint
+}
+
+func (c *ChangeStats) track(previous, current *restic.Node) {
+	switch {
+	case previous == nil:
+		c.New++
+	case previous.Equals(*current):
+		c.Unchanged++
+	default:
+		c.Changed++
+	}
+}
+
 // Archiver saves a directory structure to the repo.
 type Archiver struct {
 	Repo         restic.Repository
@@ -61,10 +93,24 @@ type Archiver struct {
 	blobSaver *BlobSaver
 	fileSaver *FileSaver
 	treeSaver *TreeSaver
+	mu        sync.Mutex
+	summary   *Summary
+	unvisited []string
+
+	// dirs contains the file info of the directories which are currently
+	// being saved, it is used to detect loops when following symlinks.
+	dirs []os.FileInfo
+
+	checkpoints  *checkpointTracker
+	checkpointID restic.ID
 
 	// Error is called for all errors that occur during backup.
 	Error ErrorFunc
 
+	// Warn is called for problems which do not affect the snapshot, like a
+	// checkpoint snapshot which could not be removed.
+	Warn func(msg string, args ...interface{})
+
 	// CompleteItem is called for all files and dirs once they have been
 	// processed successfully. The parameter item contains the path as it will
 	// be in the snapshot after saving. s contains some statistics about this
@@ -88,8 +134,53 @@ type Archiver struct {
 	// default.
 	WithAtime bool
 
+	// WithInodeFlags configures if the inode flags and the birth time of files
+	// and directories should be saved on Linux. Reading them requires opening
+	// each file, so it's off by default.
+	WithInodeFlags bool
+
 	// Flags controlling change detection. See doc/040_backup.rst for details.
 	ChangeIgnoreFlags uint
+
+	// RetryChangedFiles configures how often a file is read again if it was
+	// modified while reading it. Files which still change are marked in the
+	// snapshot. Zero disables the check.
+	RetryChangedFiles uint
+
+	// Deadline is the time after which no more files and directories are
+	// read. Items which have not been visited until then are taken from the
+	// parent snapshot if possible, and the snapshot is marked as partial.
+	Deadline time.Time
+
+	// CheckpointInterval is the interval in which a checkpoint snapshot
+	// containing all items completed so far is written. Each checkpoint
+	// replaces the previous one and the last checkpoint is removed once the
+	// final snapshot has been saved, together with checkpoints left behind by
+	// interrupted backups of the same paths. Zero disables checkpoints.
+	CheckpointInterval time.Duration
+
+	// FileHash is the name of the algorithm used to compute a checksum of the
+	// whole content of each file, see restic.NewFileHash. Unchanged files
+	// are read again if the node in the parent snapshot has no such
+	// checksum. If empty, no checksums are computed.
+	FileHash string
+
+	// Dereference configures the archiver to save the files and directories
+	// symbolic links point to instead of the links themselves.
+	Dereference bool
+
+	// DereferenceArgs is like Dereference, but only applies to symbolic links
+	// which are passed as targets to Snapshot.
+	DereferenceArgs bool
+
+	// SelectXattr selects the extended attributes which are saved. If nil,
+	// all extended attributes are saved.
+	SelectXattr restic.XattrSelectFunc
+
+	// ChangedFiles lists the items which have changed since the parent
+	// snapshot. If set, all other items are taken from the parent snapshot
+	// without accessing the file system.
+	ChangedFiles *ChangedFiles
 }
 
 // Flags for the ChangeIgnoreFlags bitfield.
@@ -155,6 +246,8 @@ func New(repo restic.Repository, fs fs.FS, opts Options) *Archiver {
 		CompleteItem: func(string, *restic.Node, *restic.Node, ItemStats, time.Duration) {},
 		StartFile:    func(string) {},
 		CompleteBlob: func(uint64) {},
+
+		summary: &Summary{},
 	}
 
 	return arch
@@ -182,9 +275,46 @@ func (arch *Archiver) error(item string, err error) error {
 	return errf
 }
 
+// warn reports a problem which does not affect the snapshot.
+func (arch *Archiver) warn(msg string, args ...interface{}) {
+	debug.Log(msg, args...)
+	if arch.Warn != nil {
+		arch.Warn(msg, args...)
+	}
+}
+
+// trackItem updates the summary of the current backup run and then calls
+// CompleteItem.
+func (arch *Archiver) trackItem(item string, previous, current *restic.Node, s ItemStats, d time.Duration) {
+	arch.mu.Lock()
+	arch.summary.ItemStats.Add(s)
+
+	// for the last item "/" and for errors, current is nil
+	if current != nil {
+		arch.summary.ProcessedBytes += current.Size
+
+		switch current.Type {
+		case "dir":
+			arch.summary.Dirs.track(previous, current)
+		case "file":
+			arch.summary.Files.track(previous, current)
+			if current.ChangedDuringBackup {
+				arch.summary.FilesChangedDuringBackup++
+			}
+		}
+	}
+	arch.mu.Unlock()
+
+	if current != nil && arch.checkpoints != nil {
+		arch.checkpoints.complete(item, current)
+	}
+
+	arch.CompleteItem(item, previous, current, s, d)
+}
+
 // nodeFromFileInfo returns the restic node from an os.FileInfo.
 func (arch *Archiver) nodeFromFileInfo(snPath, filename string, fi os.FileInfo) (*restic.Node, error) {
-	node, err := restic.NodeFromFileInfo(filename, fi)
+	node, err := restic.NodeFromFileInfo(filename, fi, arch.SelectXattr, arch.WithInodeFlags)
 	if !arch.WithAtime {
 		node.AccessTime = node.ModTime
 	}
@@ -225,14 +355,26 @@ func (arch *Archiver) wrapLoadTreeError(id restic.ID, err error) error {
 // SaveDir stores a directory in the repo and returns the node. snPath is the
 // path within the current snapshot.
 func (arch *Archiver) SaveDir(ctx context.Context, snPath string, dir string, fi os.FileInfo, previous *restic.Tree, complete CompleteFunc) (d FutureNode, err error) {
+	return arch.saveDir(ctx, snPath, dir, fi, previous, complete, fs.O_NOFOLLOW)
+}
+
+// saveDir is like SaveDir, flags are passed to fs.OpenFile when reading the
+// directory.
+func (arch *Archiver) saveDir(ctx context.Context, snPath string, dir string, fi os.FileInfo, previous *restic.Tree, complete CompleteFunc, flags int) (d FutureNode, err error) {
 	debug.Log("%v %v", snPath, dir)
 
 	treeNode, err := arch.nodeFromFileInfo(snPath, dir, fi)
 	if err != nil {
 		return FutureNode{}, err
 	}
+	if arch.checkpoints != nil {
+		arch.checkpoints.enterDir(snPath, treeNode)
+	}
+
+	arch.enterDir(fi)
+	defer arch.leaveDir()
 
-	names, err := readdirnames(arch.FS, dir, fs.O_NOFOLLOW)
+	names, err := readdirnames(arch.FS, dir, flags)
 	if err != nil {
 		return FutureNode{}, err
 	}
@@ -240,13 +382,20 @@ func (arch *Archiver) SaveDir(ctx context.Context, snPath string, dir string, fi
 
 	nodes := make([]FutureNode, 0, len(names))
 
-	for _, name := range names {
+	for i, name := range names {
 		// test if context has been cancelled
 		if ctx.Err() != nil {
 			debug.Log("context has been cancelled, aborting")
 			return FutureNode{}, ctx.Err()
 		}
 
+		if arch.deadlineExceeded() {
+			debug.Log("deadline exceeded, %d of %d entries in %v not visited", len(names)-i, len(names), dir)
+			arch.addUnvisited(snPath)
+			nodes = append(nodes, arch.reusePreviousEntries(snPath, dir, names[i:], previous)...)
+			break
+		}
+
 		pathname := arch.FS.Join(dir, name)
 		oldNode := previous.Find(name)
 		snItem := join(snPath, name)
@@ -342,6 +491,12 @@ func (arch *Archiver) allBlobsPresent(previous *restic.Node) bool {
 //
 // snPath is the path within the current snapshot.
 func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous *restic.Node) (fn FutureNode, excluded bool, err error) {
+	return arch.save(ctx, snPath, target, previous, arch.Dereference)
+}
+
+// save is like Save. If dereference is true and target is a symbolic link,
+// the file or directory the link points to is saved instead.
+func (arch *Archiver) save(ctx context.Context, snPath, target string, previous *restic.Node, dereference bool) (fn FutureNode, excluded bool, err error) {
 	start := time.Now()
 
 	debug.Log("%v target %q, previous %v", snPath, target, previous)
@@ -356,6 +511,24 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 		return FutureNode{}, true, nil
 	}
 
+	if arch.deadlineExceeded() {
+		debug.Log("deadline exceeded, %v not visited", target)
+		arch.addUnvisited(snPath)
+		if previous == nil {
+			return FutureNode{}, true, nil
+		}
+		fn, ok := arch.reusePrevious(snPath, target, previous, start)
+		return fn, !ok, nil
+	}
+
+	if previous != nil && arch.ChangedFiles != nil && arch.ChangedFiles.Unchanged(abstarget) {
+		fn, ok := arch.reusePrevious(snPath, target, previous, start)
+		if ok {
+			debug.Log("%v is not in the list of changed files, using node from parent snapshot", target)
+			return fn, false, nil
+		}
+	}
+
 	// get file info and run remaining select functions that require file information
 	fi, err := arch.FS.Lstat(target)
 	if err != nil {
@@ -366,6 +539,33 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 		}
 		return FutureNode{}, true, nil
 	}
+
+	dereferenced := false
+	if dereference && fi.Mode()&os.ModeSymlink != 0 {
+		fi, err = arch.FS.Stat(target)
+		if err != nil {
+			debug.Log("stat() for symlink %v returned error: %v", target, err)
+			if errors.Is(err, os.ErrNotExist) {
+				err = errors.New("dangling symlink, target does not exist")
+			} else {
+				err = errors.Wrap(err, "dereferencing symlink")
+			}
+			err = arch.error(abstarget, err)
+			if err != nil {
+				return FutureNode{}, false, err
+			}
+			return FutureNode{}, true, nil
+		}
+		debug.Log("dereferenced symlink %v", target)
+		dereferenced = true
+	}
+
+	// only follow symlinks when opening the target if it was dereferenced
+	openFlags := fs.O_NOFOLLOW
+	if dereferenced {
+		openFlags = 0
+	}
+
 	if !arch.Select(abstarget, fi) {
 		debug.Log("%v is excluded", target)
 		return FutureNode{}, true, nil
@@ -377,10 +577,10 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 
 		// check if the file has not changed before performing a fopen operation (more expensive, specially
 		// in network filesystems)
-		if previous != nil && !fileChanged(fi, previous, arch.ChangeIgnoreFlags) {
+		if previous != nil && !fileChanged(fi, previous, arch.ChangeIgnoreFlags) && arch.hasFileHash(previous) {
 			if arch.allBlobsPresent(previous) {
 				debug.Log("%v hasn't changed, using old list of blobs", target)
-				arch.CompleteItem(snPath, previous, previous, ItemStats{}, time.Since(start))
+				arch.trackItem(snPath, previous, previous, ItemStats{}, time.Since(start))
 				arch.CompleteBlob(previous.Size)
 				node, err := arch.nodeFromFileInfo(snPath, target, fi)
 				if err != nil {
@@ -389,6 +589,7 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 
 				// copy list of blobs
 				node.Content = previous.Content
+				node.FileHash = previous.FileHash
 
 				fn = newFutureNodeWithResult(futureNodeResult{
 					snPath: snPath,
@@ -409,7 +610,7 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 
 		// reopen file and do an fstat() on the open file to check it is still
 		// a file (and has not been exchanged for e.g. a symlink)
-		file, err := arch.FS.OpenFile(target, fs.O_RDONLY|fs.O_NOFOLLOW, 0)
+		file, err := arch.FS.OpenFile(target, fs.O_RDONLY|openFlags, 0)
 		if err != nil {
 			debug.Log("Openfile() for %v returned error: %v", target, err)
 			err = arch.error(abstarget, err)
@@ -445,14 +646,23 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 		fn = arch.fileSaver.Save(ctx, snPath, target, file, fi, func() {
 			arch.StartFile(snPath)
 		}, func() {
-			arch.CompleteItem(snPath, nil, nil, ItemStats{}, 0)
+			arch.trackItem(snPath, nil, nil, ItemStats{}, 0)
 		}, func(node *restic.Node, stats ItemStats) {
-			arch.CompleteItem(snPath, previous, node, stats, time.Since(start))
+			arch.trackItem(snPath, previous, node, stats, time.Since(start))
 		})
 
 	case fi.IsDir():
 		debug.Log("  %v dir", target)
 
+		if dereferenced && isParent(arch.dirs, fi) {
+			err = errors.New("symlink points to a parent directory, refusing to archive it to avoid a loop")
+			err = arch.error(abstarget, err)
+			if err != nil {
+				return FutureNode{}, false, err
+			}
+			return FutureNode{}, true, nil
+		}
+
 		snItem := snPath + "/"
 		oldSubtree, err := arch.loadSubtree(ctx, previous)
 		if err != nil {
@@ -462,10 +672,10 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 			return FutureNode{}, false, err
 		}
 
-		fn, err = arch.SaveDir(ctx, snPath, target, fi, oldSubtree,
+		fn, err = arch.saveDir(ctx, snPath, target, fi, oldSubtree,
 			func(node *restic.Node, stats ItemStats) {
-				arch.CompleteItem(snItem, previous, node, stats, time.Since(start))
-			})
+				arch.trackItem(snItem, previous, node, stats, time.Since(start))
+			}, openFlags)
 		if err != nil {
 			debug.Log("SaveDir for %v returned error: %v", snPath, err)
 			return FutureNode{}, false, err
@@ -494,6 +704,75 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 	return fn, false, nil
 }
 
+// reusePrevious returns a FutureNode for the node of the parent snapshot
+// without accessing the file system. It returns false if the data referenced
+// by previous is not available in the repository. The content of a directory
+// is taken over as is without loading it, the select functions are not applied
+// to it and the items it contains are not included in the summary.
+func (arch *Archiver) reusePrevious(snPath, target string, previous *restic.Node, start time.Time) (FutureNode, bool) {
+	switch previous.Type {
+	case "file":
+		if !arch.allBlobsPresent(previous) {
+			return FutureNode{}, false
+		}
+		arch.trackItem(snPath, previous, previous, ItemStats{}, time.Since(start))
+		arch.CompleteBlob(previous.Size)
+
+	case "dir":
+		if previous.Subtree == nil || !arch.Repo.Index().Has(restic.BlobHandle{ID: *previous.Subtree, Type: restic.TreeBlob}) {
+			return FutureNode{}, false
+		}
+		arch.trackItem(snPath+"/", previous, previous, ItemStats{}, time.Since(start))
+	}
+
+	node := *previous
+	node.Name = path.Base(snPath)
+
+	return newFutureNodeWithResult(futureNodeResult{
+		snPath: snPath,
+		target: target,
+		node:   &node,
+	}), true
+}
+
+// deadlineExceeded returns true if arch.Deadline is set and has passed.
+func (arch *Archiver) deadlineExceeded() bool {
+	return !arch.Deadline.IsZero() && time.Now().After(arch.Deadline)
+}
+
+// addUnvisited records that snPath was not backed up completely.
+func (arch *Archiver) addUnvisited(snPath string) {
+	arch.mu.Lock()
+	arch.unvisited = append(arch.unvisited, snPath)
+	arch.mu.Unlock()
+}
+
+// reusePreviousEntries returns the nodes from the parent snapshot for the
+// entries names of the directory dir, which have not been visited.
+func (arch *Archiver) reusePreviousEntries(snPath, dir string, names []string, previous *restic.Tree) []FutureNode {
+	var nodes []FutureNode
+	start := time.Now()
+
+	for _, name := range names {
+		oldNode := previous.Find(name)
+		if oldNode == nil {
+			continue
+		}
+
+		pathname := arch.FS.Join(dir, name)
+		abstarget, err := arch.FS.Abs(pathname)
+		if err != nil || !arch.SelectByName(abstarget) {
+			continue
+		}
+
+		if fn, ok := arch.reusePrevious(join(snPath, name), pathname, oldNode, start); ok {
+			nodes = append(nodes, fn)
+		}
+	}
+
+	return nodes
+}
+
 // fileChanged tries to detect whether a file's content has changed compared
 // to the contents of node, which describes the same path in the parent backup.
 // It should only be run for regular files.
@@ -504,6 +783,9 @@ func fileChanged(fi os.FileInfo, node *restic.Node, ignoreFlags uint) bool {
 	case node.Type != "file":
 		// We're only called for regular files, so this is a type change.
 		return true
+	case node.ChangedDuringBackup:
+		// the stored content may be inconsistent, read the file again
+		return true
 	case uint64(fi.Size()) != node.Size:
 		return true
 	case !fi.ModTime().Equal(node.ModTime):
@@ -524,6 +806,22 @@ func fileChanged(fi os.FileInfo, node *restic.Node, ignoreFlags uint) bool {
 	return false
 }
 
+// enterDir records that the directory described by fi is being saved.
+func (arch *Archiver) enterDir(fi os.FileInfo) {
+	arch.dirs = append(arch.dirs, fi)
+}
+
+// leaveDir removes the directory recorded last by enterDir.
+func (arch *Archiver) leaveDir() {
+	arch.dirs = arch.dirs[:len(arch.dirs)-1]
+}
+
+// hasFileHash returns true if node contains a checksum computed with the
+// algorithm configured in arch.FileHash, or if no checksum is requested.
+func (arch *Archiver) hasFileHash(node *restic.Node) bool {
+	return arch.FileHash == "" || node.FileHashAlgorithm() == arch.FileHash
+}
+
 // join returns all elements separated with a forward slash.
 func join(elem ...string) string {
 	return path.Join(elem...)
@@ -565,6 +863,12 @@ func (arch *Archiver) SaveTree(ctx context.Context, snPath string, atree *Tree,
 		if err != nil {
 			return FutureNode{}, 0, err
 		}
+		if arch.checkpoints != nil {
+			arch.checkpoints.enterDir(snPath, node)
+		}
+
+		arch.enterDir(fi)
+		defer arch.leaveDir()
 	} else {
 		// fake root node
 		node = &restic.Node{}
@@ -585,7 +889,7 @@ func (arch *Archiver) SaveTree(ctx context.Context, snPath string, atree *Tree,
 
 		// this is a leaf node
 		if subatree.Leaf() {
-			fn, excluded, err := arch.Save(ctx, join(snPath, name), subatree.Path, previous.Find(name))
+			fn, excluded, err := arch.save(ctx, join(snPath, name), subatree.Path, previous.Find(name), arch.Dereference || arch.DereferenceArgs)
 
 			if err != nil {
 				err = arch.error(subatree.Path, err)
@@ -620,7 +924,7 @@ func (arch *Archiver) SaveTree(ctx context.Context, snPath string, atree *Tree,
 
 		// not a leaf node, archive subtree
 		fn, _, err := arch.SaveTree(ctx, join(snPath, name), &subatree, oldSubtree, func(n *restic.Node, is ItemStats) {
-			arch.CompleteItem(snItem, oldNode, n, is, time.Since(start))
+			arch.trackItem(snItem, oldNode, n, is, time.Since(start))
 		})
 		if err != nil {
 			return FutureNode{}, 0, err
@@ -683,14 +987,25 @@ func resolveRelativeTargets(filesys fs.FS, targets []string) ([]string, error) {
 	return result, nil
 }
 
+// PartialTag is added to snapshots which are incomplete because the deadline
+// of the archiver was exceeded.
+const PartialTag = "partial"
+
 // SnapshotOptions collect attributes for a new snapshot.
 type SnapshotOptions struct {
 	Tags           restic.TagList
+	Labels         map[string]string
+	Description    string
 	Hostname       string
 	Excludes       []string
 	Time           time.Time
 	ParentSnapshot *restic.Snapshot
 	ProgramVersion string
+	// BackupStart is the time the backup run started. It is stored in the
+	// summary of the snapshot.
+	BackupStart time.Time
+	// SkipIfUnchanged omits the snapshot creation if it is identical to the parent snapshot.
+	SkipIfUnchanged bool
 }
 
 // loadParentTree loads a tree referenced by snapshot id. If id is null, nil is returned.
@@ -724,6 +1039,9 @@ func (arch *Archiver) runWorkers(ctx context.Context, wg *errgroup.Group) {
 		arch.Options.ReadConcurrency, arch.Options.SaveBlobConcurrency)
 	arch.fileSaver.CompleteBlob = arch.CompleteBlob
 	arch.fileSaver.NodeFromFileInfo = arch.nodeFromFileInfo
+	arch.fileSaver.RetryChangedFiles = arch.RetryChangedFiles
+	arch.fileSaver.FileHash = arch.FileHash
+	arch.fileSaver.hardlinks = newHardlinkIndex()
 
 	arch.treeSaver = NewTreeSaver(ctx, wg, arch.Options.SaveTreeConcurrency, arch.blobSaver.Save, arch.Error)
 }
@@ -737,16 +1055,33 @@ func (arch *Archiver) stopWorkers() {
 	arch.treeSaver = nil
 }
 
-// Snapshot saves several targets and returns a snapshot.
-func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts SnapshotOptions) (*restic.Snapshot, restic.ID, error) {
+// Snapshot saves several targets and returns a snapshot. If
+// opts.SkipIfUnchanged is set and the resulting tree is identical to the tree
+// of the parent snapshot, no snapshot is written and a nil snapshot with a
+// null ID is returned.
+func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts SnapshotOptions) (*restic.Snapshot, restic.ID, *Summary, error) {
+	arch.summary = &Summary{
+		BackupStart: opts.BackupStart,
+	}
+	arch.unvisited = nil
+	arch.dirs = nil
+	arch.checkpoints = nil
+	arch.checkpointID = restic.ID{}
+	if arch.CheckpointInterval > 0 {
+		arch.checkpoints = newCheckpointTracker()
+	}
+	if arch.summary.BackupStart.IsZero() {
+		arch.summary.BackupStart = time.Now()
+	}
+
 	cleanTargets, err := resolveRelativeTargets(arch.FS, targets)
 	if err != nil {
-		return nil, restic.ID{}, err
+		return nil, restic.ID{}, nil, err
 	}
 
 	atree, err := NewTree(arch.FS, cleanTargets)
 	if err != nil {
-		return nil, restic.ID{}, err
+		return nil, restic.ID{}, nil, err
 	}
 
 	var rootTreeID restic.ID
@@ -758,12 +1093,20 @@ func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts Snaps
 		wg, wgCtx := errgroup.WithContext(wgUpCtx)
 		start := time.Now()
 
+		checkpointsDone := make(chan struct{})
+		if arch.checkpoints != nil {
+			wg.Go(func() error {
+				return arch.runCheckpoints(wgCtx, checkpointsDone, targets, opts)
+			})
+		}
+
 		wg.Go(func() error {
+			defer close(checkpointsDone)
 			arch.runWorkers(wgCtx, wg)
 
 			debug.Log("starting snapshot")
 			fn, nodeCount, err := arch.SaveTree(wgCtx, "/", atree, arch.loadParentTree(wgCtx, opts.ParentSnapshot), func(_ *restic.Node, is ItemStats) {
-				arch.CompleteItem("/", nil, nil, is, time.Since(start))
+				arch.trackItem("/", nil, nil, is, time.Since(start))
 			})
 			if err != nil {
 				return err
@@ -799,25 +1142,67 @@ func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts Snaps
 	})
 	err = wgUp.Wait()
 	if err != nil {
-		return nil, restic.ID{}, err
+		return nil, restic.ID{}, nil, err
 	}
 
 	sn, err := restic.NewSnapshot(targets, opts.Tags, opts.Hostname, opts.Time)
 	if err != nil {
-		return nil, restic.ID{}, err
+		return nil, restic.ID{}, nil, err
+	}
+
+	if opts.ParentSnapshot != nil && opts.SkipIfUnchanged {
+		ps := opts.ParentSnapshot
+		if ps.Tree != nil && rootTreeID.Equal(*ps.Tree) {
+			arch.removeCheckpoint(ctx)
+			if arch.CheckpointInterval > 0 {
+				arch.removeStaleCheckpoints(ctx, sn)
+			}
+			arch.summary.BackupEnd = time.Now()
+			return nil, restic.ID{}, arch.summary, nil
+		}
 	}
 
 	sn.ProgramVersion = opts.ProgramVersion
 	sn.Excludes = opts.Excludes
+	sn.Labels = opts.Labels
+	sn.Description = opts.Description
+	if len(arch.unvisited) > 0 {
+		sort.Strings(arch.unvisited)
+		sn.Unvisited = arch.unvisited
+		sn.AddTags([]string{PartialTag})
+	}
 	if opts.ParentSnapshot != nil {
 		sn.Parent = opts.ParentSnapshot.ID()
 	}
 	sn.Tree = &rootTreeID
+	arch.summary.BackupEnd = time.Now()
+	sn.Summary = &restic.SnapshotSummary{
+		BackupStart: arch.summary.BackupStart,
+		BackupEnd:   arch.summary.BackupEnd,
+
+		FilesNew:                 arch.summary.Files.New,
+		FilesChanged:             arch.summary.Files.Changed,
+		FilesUnmodified:          arch.summary.Files.Unchanged,
+		FilesChangedDuringBackup: arch.summary.FilesChangedDuringBackup,
+		DirsNew:                  arch.summary.Dirs.New,
+		DirsChanged:              arch.summary.Dirs.Changed,
+		DirsUnmodified:           arch.summary.Dirs.Unchanged,
+		DataBlobs:                arch.summary.ItemStats.DataBlobs,
+		TreeBlobs:                arch.summary.ItemStats.TreeBlobs,
+		DataAdded:                arch.summary.ItemStats.DataSize + arch.summary.ItemStats.TreeSize,
+		DataAddedPacked:          arch.summary.ItemStats.DataSizeInRepo + arch.summary.ItemStats.TreeSizeInRepo,
+		TotalFilesProcessed:      arch.summary.Files.New + arch.summary.Files.Changed + arch.summary.Files.Unchanged,
+		TotalBytesProcessed:      arch.summary.ProcessedBytes,
+	}
 
 	id, err := restic.SaveSnapshot(ctx, arch.Repo, sn)
 	if err != nil {
-		return nil, restic.ID{}, err
+		return nil, restic.ID{}, nil, err
+	}
+	arch.removeCheckpoint(ctx)
+	if arch.CheckpointInterval > 0 {
+		arch.removeStaleCheckpoints(ctx, sn)
 	}
 
-	return sn, id, nil
+	return sn, id, arch.summary, nil
 }
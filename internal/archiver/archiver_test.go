@@ -3,10 +3,12 @@ package archiver
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -553,7 +555,7 @@ func rename(t testing.TB, oldname, newname string) {
 }
 
 func nodeFromFI(t testing.TB, filename string, fi os.FileInfo) *restic.Node {
-	node, err := restic.NodeFromFileInfo(filename, fi)
+	node, err := restic.NodeFromFileInfo(filename, fi, nil, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -730,6 +732,15 @@ func TestFilChangedSpecialCases(t *testing.T) {
 			t.Fatal("node with changed type detected as unchanged")
 		}
 	})
+
+	t.Run("changed-during-backup", func(t *testing.T) {
+		fi := lstat(t, filename)
+		node := nodeFromFI(t, filename, fi)
+		node.ChangedDuringBackup = true
+		if !fileChanged(fi, node, 0) {
+			t.Fatal("node changed during backup detected as unchanged")
+		}
+	})
 }
 
 func TestArchiverSaveDir(t *testing.T) {
@@ -1395,7 +1406,7 @@ func TestArchiverSnapshot(t *testing.T) {
 			}
 
 			t.Logf("targets: %v", targets)
-			sn, snapshotID, err := arch.Snapshot(ctx, targets, SnapshotOptions{Time: time.Now()})
+			sn, snapshotID, _, err := arch.Snapshot(ctx, targets, SnapshotOptions{Time: time.Now()})
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -1543,7 +1554,7 @@ func TestArchiverSnapshotSelect(t *testing.T) {
 			defer back()
 
 			targets := []string{"."}
-			_, snapshotID, err := arch.Snapshot(ctx, targets, SnapshotOptions{Time: time.Now()})
+			_, snapshotID, _, err := arch.Snapshot(ctx, targets, SnapshotOptions{Time: time.Now()})
 			if test.err != "" {
 				if err == nil {
 					t.Fatalf("expected error not found, got %v, wanted %q", err, test.err)
@@ -1618,8 +1629,10 @@ func (f MockFile) Read(p []byte) (int, error) {
 
 func TestArchiverParent(t *testing.T) {
 	var tests = []struct {
-		src  TestDir
-		read map[string]int // tracks number of times a file must have been read
+		src         TestDir
+		read        map[string]int // tracks number of times a file must have been read
+		statInitial Summary
+		statSecond  Summary
 	}{
 		{
 			src: TestDir{
@@ -1628,6 +1641,16 @@ func TestArchiverParent(t *testing.T) {
 			read: map[string]int{
 				"targetfile": 1,
 			},
+			statInitial: Summary{
+				Files:          ChangeStats{1, 0, 0},
+				Dirs:           ChangeStats{0, 0, 0},
+				ProcessedBytes: 2102152,
+			},
+			statSecond: Summary{
+				Files:          ChangeStats{0, 0, 1},
+				Dirs:           ChangeStats{0, 0, 0},
+				ProcessedBytes: 2102152,
+			},
 		},
 	}
 
@@ -1648,10 +1671,16 @@ func TestArchiverParent(t *testing.T) {
 			back := restictest.Chdir(t, tempdir)
 			defer back()
 
-			firstSnapshot, firstSnapshotID, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+			firstSnapshot, firstSnapshotID, summary, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
 			if err != nil {
 				t.Fatal(err)
 			}
+			restictest.Equals(t, test.statInitial.Files, summary.Files)
+			restictest.Equals(t, test.statInitial.Dirs, summary.Dirs)
+			restictest.Equals(t, test.statInitial.ProcessedBytes, summary.ProcessedBytes)
+			restictest.Assert(t, firstSnapshot.Summary != nil, "snapshot summary is missing")
+			restictest.Equals(t, test.statInitial.Files.New, firstSnapshot.Summary.FilesNew)
+			restictest.Equals(t, test.statInitial.ProcessedBytes, firstSnapshot.Summary.TotalBytesProcessed)
 
 			t.Logf("first backup saved as %v", firstSnapshotID.Str())
 			t.Logf("testfs: %v", testFS)
@@ -1678,10 +1707,14 @@ func TestArchiverParent(t *testing.T) {
 				Time:           time.Now(),
 				ParentSnapshot: firstSnapshot,
 			}
-			_, secondSnapshotID, err := arch.Snapshot(ctx, []string{"."}, opts)
+			secondSnapshot, secondSnapshotID, summary, err := arch.Snapshot(ctx, []string{"."}, opts)
 			if err != nil {
 				t.Fatal(err)
 			}
+			restictest.Equals(t, test.statSecond.Files, summary.Files)
+			restictest.Equals(t, test.statSecond.Dirs, summary.Dirs)
+			restictest.Equals(t, test.statSecond.ProcessedBytes, summary.ProcessedBytes)
+			restictest.Equals(t, test.statSecond.Files.Unchanged, secondSnapshot.Summary.FilesUnmodified)
 
 			// check that all files still been read exactly once
 			TestWalkFiles(t, ".", test.src, func(filename string, item interface{}) error {
@@ -1709,6 +1742,449 @@ func TestArchiverParent(t *testing.T) {
 	}
 }
 
+func TestArchiverFileHash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	content := "file content"
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{
+		"file": TestFile{Content: content},
+	})
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	testFS := &MockFS{
+		FS:        fs.Track{FS: fs.Local{}},
+		bytesRead: make(map[string]int),
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	want := restic.FormatFileHash(restic.FileHashSHA256, sum[:])
+
+	var parent *restic.Snapshot
+	for i, test := range []struct {
+		fileHash string
+		read     int
+		want     string
+	}{
+		{"", 1, ""},
+		// the parent has no hash, the file is read again
+		{restic.FileHashSHA256, 2, want},
+		// the hash is taken from the parent
+		{restic.FileHashSHA256, 2, want},
+		// the hash is kept even if it is not requested
+		{"", 2, want},
+	} {
+		arch := New(repo, testFS, Options{})
+		arch.FileHash = test.fileHash
+
+		sn, _, _, err := arch.Snapshot(ctx, []string{"file"}, SnapshotOptions{Time: time.Now(), ParentSnapshot: parent})
+		restictest.OK(t, err)
+		parent = sn
+
+		tree, err := restic.LoadTree(ctx, repo, *sn.Tree)
+		restictest.OK(t, err)
+		node := tree.Find("file")
+		restictest.Assert(t, node != nil, "snapshot %d: file not found", i)
+
+		restictest.Equals(t, test.want, node.FileHash)
+		restictest.Equals(t, test.read*len(content), testFS.bytesRead["file"])
+	}
+}
+
+func TestArchiverChangedFiles(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{
+		"listed": TestFile{Content: "foo"},
+		"subdir": TestDir{
+			"unlisted": TestFile{Content: "bar"},
+		},
+		"other": TestDir{
+			"file":    TestFile{Content: "baz"},
+			"removed": TestFile{Content: "removed"},
+		},
+	})
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	parent, _, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+	restictest.OK(t, err)
+
+	// modify all files, but only report some of them as changed
+	restictest.OK(t, os.WriteFile("listed", []byte("foo changed"), 0644))
+	restictest.OK(t, os.WriteFile(filepath.Join("subdir", "unlisted"), []byte("bar changed"), 0644))
+	restictest.OK(t, os.WriteFile(filepath.Join("other", "file"), []byte("baz changed"), 0644))
+	restictest.OK(t, os.Remove(filepath.Join("other", "removed")))
+	restictest.OK(t, os.WriteFile(filepath.Join("other", "new"), []byte("new"), 0644))
+
+	arch = New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	arch.ChangedFiles, err = NewChangedFiles(arch.FS, []string{"listed", "other"})
+	restictest.OK(t, err)
+
+	_, id, summary, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), ParentSnapshot: parent})
+	restictest.OK(t, err)
+
+	// the content of subdir is neither read nor counted
+	restictest.Equals(t, ChangeStats{New: 1, Changed: 2, Unchanged: 0}, summary.Files)
+	restictest.Equals(t, uint64(len("foo changed")+len("baz changed")+len("new")), summary.ProcessedBytes)
+
+	TestEnsureSnapshot(t, repo, id, TestDir{
+		"listed": TestFile{Content: "foo changed"},
+		"subdir": TestDir{
+			// not listed, thus taken from the parent snapshot
+			"unlisted": TestFile{Content: "bar"},
+		},
+		"other": TestDir{
+			"file": TestFile{Content: "baz changed"},
+			"new":  TestFile{Content: "new"},
+		},
+	})
+}
+
+func TestArchiverDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{
+		"dir": TestDir{
+			"a": TestFile{Content: "a"},
+			"b": TestFile{Content: "b"},
+			"c": TestFile{Content: "c"},
+		},
+	})
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	parent, _, _, err := arch.Snapshot(ctx, []string{"dir"}, SnapshotOptions{Time: time.Now()})
+	restictest.OK(t, err)
+
+	for _, name := range []string{"a", "b", "c"} {
+		restictest.OK(t, os.WriteFile(filepath.Join("dir", name), []byte(name+" changed"), 0644))
+	}
+
+	// exceed the deadline while saving the second file
+	arch = New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	arch.Deadline = time.Now().Add(time.Hour)
+	arch.Select = func(item string, fi os.FileInfo) bool {
+		if filepath.Base(item) == "b" {
+			arch.Deadline = time.Now().Add(-time.Second)
+		}
+		return true
+	}
+
+	sn, id, _, err := arch.Snapshot(ctx, []string{"dir"}, SnapshotOptions{Time: time.Now(), ParentSnapshot: parent})
+	restictest.OK(t, err)
+
+	if !sn.HasTags([]string{PartialTag}) {
+		t.Errorf("snapshot does not have tag %q, tags: %v", PartialTag, sn.Tags)
+	}
+	restictest.Equals(t, []string{"/dir"}, sn.Unvisited)
+
+	TestEnsureSnapshot(t, repo, id, TestDir{
+		"dir": TestDir{
+			"a": TestFile{Content: "a changed"},
+			"b": TestFile{Content: "b changed"},
+			// not visited, thus taken from the parent snapshot
+			"c": TestFile{Content: "c"},
+		},
+	})
+
+	// without a parent snapshot, nothing can be saved
+	arch = New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	arch.Deadline = time.Now().Add(-time.Second)
+	_, _, _, err = arch.Snapshot(ctx, []string{"dir"}, SnapshotOptions{Time: time.Now()})
+	if err == nil {
+		t.Fatal("expected error for empty snapshot, got nil")
+	}
+}
+
+func TestArchiverDereference(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks are not supported on Windows")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{
+		"dir": TestDir{
+			"file": TestFile{Content: "foo"},
+			"loop": TestSymlink{Target: "."},
+		},
+		"link-dir":  TestSymlink{Target: "dir"},
+		"link-file": TestSymlink{Target: filepath.Join("dir", "file")},
+		"dangling":  TestSymlink{Target: "missing"},
+	})
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	var tests = []struct {
+		name            string
+		dereference     bool
+		dereferenceArgs bool
+		want            TestDir
+		errors          []string
+	}{
+		{
+			name: "none",
+			want: TestDir{
+				"dir": TestDir{
+					"file": TestFile{Content: "foo"},
+					"loop": TestSymlink{Target: "."},
+				},
+				"link-dir":  TestSymlink{Target: "dir"},
+				"link-file": TestSymlink{Target: filepath.Join("dir", "file")},
+				"dangling":  TestSymlink{Target: "missing"},
+			},
+		},
+		{
+			name:            "args",
+			dereferenceArgs: true,
+			want: TestDir{
+				"dir": TestDir{
+					"file": TestFile{Content: "foo"},
+					"loop": TestSymlink{Target: "."},
+				},
+				"link-dir": TestDir{
+					"file": TestFile{Content: "foo"},
+					"loop": TestSymlink{Target: "."},
+				},
+				"link-file": TestFile{Content: "foo"},
+			},
+			errors: []string{"dangling"},
+		},
+		{
+			name:        "all",
+			dereference: true,
+			want: TestDir{
+				"dir": TestDir{
+					"file": TestFile{Content: "foo"},
+				},
+				"link-dir": TestDir{
+					"file": TestFile{Content: "foo"},
+				},
+				"link-file": TestFile{Content: "foo"},
+			},
+			errors: []string{"dangling", filepath.Join("dir", "loop"), filepath.Join("link-dir", "loop")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+			arch.Dereference = test.dereference
+			arch.DereferenceArgs = test.dereferenceArgs
+
+			var errs []string
+			arch.Error = func(item string, err error) error {
+				t.Logf("error for %v: %v", item, err)
+				rel, relErr := filepath.Rel(tempdir, item)
+				restictest.OK(t, relErr)
+				errs = append(errs, rel)
+				return nil
+			}
+
+			_, id, _, err := arch.Snapshot(ctx, []string{"dir", "link-dir", "link-file", "dangling"}, SnapshotOptions{Time: time.Now()})
+			restictest.OK(t, err)
+
+			TestEnsureSnapshot(t, repo, id, test.want)
+			sort.Strings(errs)
+			sort.Strings(test.errors)
+			restictest.Equals(t, test.errors, errs)
+		})
+	}
+}
+
+func listSnapshots(t testing.TB, repo restic.Repository) restic.IDs {
+	var ids restic.IDs
+	err := repo.List(context.TODO(), restic.SnapshotFile, func(id restic.ID, _ int64) error {
+		ids = append(ids, id)
+		return nil
+	})
+	restictest.OK(t, err)
+	return ids
+}
+
+func TestArchiverCheckpoint(t *testing.T) {
+	src := TestDir{
+		"dir1": TestDir{
+			"a": TestFile{Content: "a"},
+		},
+		"dir2": TestDir{
+			"c": TestFile{Content: "c"},
+		},
+	}
+
+	for _, tc := range []struct {
+		name  string
+		abort bool
+	}{
+		{name: "success"},
+		{name: "abort", abort: true},
+	} {
+		abort := tc.abort
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			tempdir, repo := prepareTempdirRepoSrc(t, src)
+			back := restictest.Chdir(t, tempdir)
+			defer back()
+
+			arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+			arch.CheckpointInterval = 10 * time.Millisecond
+			arch.Select = func(item string, fi os.FileInfo) bool {
+				if filepath.Base(item) != "c" {
+					return true
+				}
+
+				// wait until a checkpoint has been written
+				for start := time.Now(); len(listSnapshots(t, repo)) == 0; {
+					if time.Since(start) > 10*time.Second {
+						t.Error("timeout waiting for checkpoint")
+						break
+					}
+					time.Sleep(10 * time.Millisecond)
+				}
+				if abort {
+					cancel()
+				}
+				return true
+			}
+
+			_, id, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+			ids := listSnapshots(t, repo)
+			if len(ids) != 1 {
+				t.Fatalf("expected one snapshot, got %v", ids)
+			}
+
+			if !abort {
+				restictest.OK(t, err)
+				restictest.Equals(t, id, ids[0])
+				TestEnsureSnapshot(t, repo, id, src)
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected error for aborted backup, got nil")
+			}
+
+			sn, err := restic.LoadSnapshot(context.TODO(), repo, ids[0])
+			restictest.OK(t, err)
+			if !sn.HasTags([]string{CheckpointTag}) {
+				t.Errorf("snapshot does not have tag %q, tags: %v", CheckpointTag, sn.Tags)
+			}
+
+			TestEnsureSnapshot(t, repo, ids[0], TestDir{
+				"dir1": TestDir{
+					"a": TestFile{Content: "a"},
+				},
+				"dir2": TestDir{},
+			})
+		})
+	}
+}
+
+func TestArchiverRemoveStaleCheckpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{"file": TestFile{Content: "foo"}})
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	sn, first, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), Hostname: "host"})
+	restictest.OK(t, err)
+
+	// checkpoints left behind by interrupted backups
+	saveCheckpoint := func(hostname string) restic.ID {
+		checkpoint := *sn
+		checkpoint.Hostname = hostname
+		checkpoint.Time = sn.Time.Add(time.Minute)
+		checkpoint.Tags = []string{CheckpointTag}
+		id, err := restic.SaveSnapshot(ctx, repo, &checkpoint)
+		restictest.OK(t, err)
+		return id
+	}
+	stale := saveCheckpoint("host")
+	other := saveCheckpoint("other")
+
+	backup := func(ts time.Time) restic.ID {
+		arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+		arch.CheckpointInterval = time.Hour
+		arch.Warn = func(msg string, args ...interface{}) {
+			t.Errorf("unexpected warning: "+msg, args...)
+		}
+		_, id, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: ts, Hostname: "host"})
+		restictest.OK(t, err)
+		return id
+	}
+
+	// the checkpoints of a concurrent backup cannot be told apart from stale ones
+	lockID, err := restic.SaveJSONUnpacked(ctx, repo, restic.LockFile, &restic.Lock{Time: time.Now(), Hostname: "other", PID: 42})
+	restictest.OK(t, err)
+	locked := backup(sn.Time.Add(time.Hour))
+	ids := restic.NewIDSet(listSnapshots(t, repo)...)
+	restictest.Assert(t, ids.Has(stale), "checkpoint %v removed while the repository is locked", stale.Str())
+	restictest.OK(t, repo.Backend().Remove(ctx, backend.Handle{Type: restic.LockFile, Name: lockID.String()}))
+
+	// the lock of the current process is ignored
+	lock, err := restic.NewLock(ctx, repo)
+	restictest.OK(t, err)
+	defer func() {
+		restictest.OK(t, lock.Unlock())
+	}()
+	id := backup(sn.Time.Add(2 * time.Hour))
+
+	ids = restic.NewIDSet(listSnapshots(t, repo)...)
+	restictest.Assert(t, !ids.Has(stale), "stale checkpoint %v not removed", stale.Str())
+	restictest.Assert(t, ids.Has(other), "checkpoint %v of other host removed", other.Str())
+	restictest.Assert(t, ids.Has(id) && ids.Has(locked) && ids.Has(first), "snapshots missing, got %v", ids)
+}
+
+func TestArchiverSkipIfUnchangedRemovesStaleCheckpoints(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{"file": TestFile{Content: "foo"}})
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	sn, first, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), Hostname: "host"})
+	restictest.OK(t, err)
+
+	checkpoint := *sn
+	checkpoint.Time = sn.Time.Add(time.Minute)
+	checkpoint.Tags = []string{CheckpointTag}
+	stale, err := restic.SaveSnapshot(ctx, repo, &checkpoint)
+	restictest.OK(t, err)
+
+	arch = New(repo, fs.Track{FS: fs.Local{}}, Options{})
+	arch.CheckpointInterval = time.Hour
+	skipped, _, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{
+		Time:            sn.Time.Add(time.Hour),
+		Hostname:        "host",
+		ParentSnapshot:  sn,
+		SkipIfUnchanged: true,
+	})
+	restictest.OK(t, err)
+	restictest.Assert(t, skipped == nil, "snapshot was not skipped")
+
+	ids := restic.NewIDSet(listSnapshots(t, repo)...)
+	restictest.Assert(t, !ids.Has(stale), "stale checkpoint %v not removed", stale.Str())
+	restictest.Assert(t, ids.Has(first), "snapshot %v missing", first.Str())
+}
+
 func TestArchiverErrorReporting(t *testing.T) {
 	ignoreErrorForBasename := func(basename string) ErrorFunc {
 		return func(item string, err error) error {
@@ -1814,7 +2290,7 @@ func TestArchiverErrorReporting(t *testing.T) {
 			arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
 			arch.Error = test.errFn
 
-			_, snapshotID, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+			_, snapshotID, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
 			if test.mustError {
 				if err != nil {
 					t.Logf("found expected error (%v), skipping further checks", err)
@@ -1887,7 +2363,7 @@ func TestArchiverContextCanceled(t *testing.T) {
 
 	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
 
-	_, snapshotID, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+	_, snapshotID, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
 
 	if err != nil {
 		t.Logf("found expected error (%v)", err)
@@ -2026,7 +2502,7 @@ func TestArchiverAbortEarlyOnError(t *testing.T) {
 				SaveBlobConcurrency: 1,
 			})
 
-			_, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+			_, _, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
 			if !errors.Is(err, test.err) {
 				t.Errorf("expected error (%v) not found, got %v", test.err, err)
 			}
@@ -2054,7 +2530,7 @@ func snapshot(t testing.TB, repo restic.Repository, fs fs.FS, parent *restic.Sna
 		Time:           time.Now(),
 		ParentSnapshot: parent,
 	}
-	snapshot, _, err := arch.Snapshot(ctx, []string{filename}, sopts)
+	snapshot, _, _, err := arch.Snapshot(ctx, []string{filename}, sopts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -2138,7 +2614,7 @@ func TestMetadataChanged(t *testing.T) {
 
 	// get metadata
 	fi := lstat(t, "testfile")
-	want, err := restic.NodeFromFileInfo("testfile", fi)
+	want, err := restic.NodeFromFileInfo("testfile", fi, nil, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -4,8 +4,15 @@
 package archiver
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"syscall"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/fs"
+	restictest "github.com/restic/restic/internal/test"
 )
 
 type wrappedFileInfo struct {
@@ -39,3 +46,48 @@ func wrapFileInfo(fi os.FileInfo) os.FileInfo {
 
 	return res
 }
+
+func TestArchiverHardlinks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	content := "content of the hardlinked file"
+	tempdir, repo := prepareTempdirRepoSrc(t, TestDir{
+		"a": TestDir{
+			"file": TestFile{Content: content},
+		},
+		"b":     TestDir{},
+		"other": TestFile{Content: "other"},
+	})
+	restictest.OK(t, os.Link(filepath.Join(tempdir, "a", "file"), filepath.Join(tempdir, "b", "file")))
+	restictest.OK(t, os.Link(filepath.Join(tempdir, "a", "file"), filepath.Join(tempdir, "link")))
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	testFS := &MockFS{
+		FS:        fs.Track{FS: fs.Local{}},
+		bytesRead: make(map[string]int),
+	}
+	arch := New(repo, testFS, Options{})
+	_, id, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+	restictest.OK(t, err)
+
+	TestEnsureSnapshot(t, repo, id, TestDir{
+		"a": TestDir{
+			"file": TestFile{Content: content},
+		},
+		"b": TestDir{
+			"file": TestFile{Content: content},
+		},
+		"link":  TestFile{Content: content},
+		"other": TestFile{Content: "other"},
+	})
+
+	// only one of the three links must have been read
+	var read int
+	for _, name := range []string{filepath.Join("a", "file"), filepath.Join("b", "file"), "link"} {
+		read += testFS.bytesRead[name]
+	}
+	restictest.Equals(t, len(content), read)
+}
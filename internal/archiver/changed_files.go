@@ -0,0 +1,75 @@
+package archiver
+
+import (
+	"github.com/restic/restic/internal/fs"
+)
+
+// ChangedFiles is a list of paths which have been modified, created or
+// removed since the parent snapshot was created. When set for an Archiver,
+// all files and directories which are neither listed themselves nor contain a
+// listed path are taken from the parent snapshot without accessing the file
+// system. The content of listed directories is read completely.
+type ChangedFiles struct {
+	fs fs.FS
+
+	// listed contains the absolute paths of all listed items
+	listed map[string]struct{}
+	// parents contains all directories which contain a listed item
+	parents map[string]struct{}
+}
+
+// NewChangedFiles returns a ChangedFiles list for the given paths. Relative
+// paths are resolved against the current directory.
+func NewChangedFiles(filesys fs.FS, paths []string) (*ChangedFiles, error) {
+	c := &ChangedFiles{
+		fs:      filesys,
+		listed:  make(map[string]struct{}, len(paths)),
+		parents: make(map[string]struct{}),
+	}
+
+	for _, p := range paths {
+		abs, err := filesys.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		c.listed[abs] = struct{}{}
+
+		for dir := filesys.Dir(abs); ; dir = filesys.Dir(dir) {
+			if _, ok := c.parents[dir]; ok {
+				break
+			}
+			c.parents[dir] = struct{}{}
+
+			if filesys.Dir(dir) == dir {
+				// reached the root directory
+				break
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// Unchanged returns true if neither the item at the absolute path abstarget
+// nor anything below it is contained in the list, and abstarget is not
+// located below a listed directory.
+func (c *ChangedFiles) Unchanged(abstarget string) bool {
+	if _, ok := c.listed[abstarget]; ok {
+		return false
+	}
+	if _, ok := c.parents[abstarget]; ok {
+		return false
+	}
+
+	for dir := c.fs.Dir(abstarget); ; dir = c.fs.Dir(dir) {
+		if _, ok := c.listed[dir]; ok {
+			return false
+		}
+
+		if c.fs.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,39 @@
+package archiver
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/restic/restic/internal/fs"
+	restictest "github.com/restic/restic/internal/test"
+)
+
+func TestChangedFilesUnchanged(t *testing.T) {
+	filesys := fs.Local{}
+	root, err := filesys.Abs(string(filepath.Separator))
+	restictest.OK(t, err)
+	p := func(elem ...string) string {
+		return filesys.Join(append([]string{root}, elem...)...)
+	}
+
+	c, err := NewChangedFiles(filesys, []string{p("home", "user", "file"), p("srv", "www")})
+	restictest.OK(t, err)
+
+	for _, test := range []struct {
+		path      string
+		unchanged bool
+	}{
+		{root, false},
+		{p("home"), false},
+		{p("home", "user"), false},
+		{p("home", "user", "file"), false},
+		{p("home", "user", "other"), true},
+		{p("home", "other"), true},
+		{p("srv", "www"), false},
+		{p("srv", "www", "sub", "index.html"), false},
+		{p("srv", "ftp"), true},
+		{p("var"), true},
+	} {
+		restictest.Equals(t, test.unchanged, c.Unchanged(test.path), "unexpected result for %v", test.path)
+	}
+}
@@ -0,0 +1,342 @@
+package archiver
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+)
+
+// CheckpointTag is added to the intermediate snapshots which are written
+// periodically during a backup.
+const CheckpointTag = "checkpoint"
+
+// checkpointDir contains the items of a directory which have been completed
+// while the directory itself has not been saved yet.
+type checkpointDir struct {
+	// node holds the metadata of the directory, it is nil for the root
+	node *restic.Node
+	// items contains all completed files and directories
+	items map[string]*restic.Node
+	// dirs contains all subdirectories which are not completed yet
+	dirs map[string]*checkpointDir
+}
+
+func newCheckpointDir(node *restic.Node) *checkpointDir {
+	return &checkpointDir{
+		node:  node,
+		items: make(map[string]*restic.Node),
+		dirs:  make(map[string]*checkpointDir),
+	}
+}
+
+// checkpointTracker keeps track of all items completed during a backup. Once
+// a directory is completed, the items below it are forgotten, so only the
+// boundary between completed and pending items is kept in memory.
+type checkpointTracker struct {
+	mu   sync.Mutex
+	root *checkpointDir
+}
+
+func newCheckpointTracker() *checkpointTracker {
+	return &checkpointTracker{root: newCheckpointDir(nil)}
+}
+
+func splitSnPath(snPath string) []string {
+	snPath = strings.Trim(snPath, "/")
+	if snPath == "" {
+		return nil
+	}
+	return strings.Split(snPath, "/")
+}
+
+// lookup returns the pending directory for the path components pc, which is
+// created if necessary. It returns nil if the directory or one of its parents
+// has already been completed.
+func (t *checkpointTracker) lookup(pc []string) *checkpointDir {
+	dir := t.root
+	for _, name := range pc {
+		if _, ok := dir.items[name]; ok {
+			return nil
+		}
+
+		sub, ok := dir.dirs[name]
+		if !ok {
+			sub = newCheckpointDir(nil)
+			dir.dirs[name] = sub
+		}
+		dir = sub
+	}
+	return dir
+}
+
+// enterDir records the metadata for the directory at snPath, which is about
+// to be saved.
+func (t *checkpointTracker) enterDir(snPath string, node *restic.Node) {
+	n := *node
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if dir := t.lookup(splitSnPath(snPath)); dir != nil {
+		dir.node = &n
+	}
+}
+
+// complete records that the item at snPath has been saved as node.
+func (t *checkpointTracker) complete(snPath string, node *restic.Node) {
+	pc := splitSnPath(snPath)
+	if len(pc) == 0 {
+		return
+	}
+
+	n := *node
+	n.Name = pc[len(pc)-1]
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	dir := t.lookup(pc[:len(pc)-1])
+	if dir == nil {
+		return
+	}
+	dir.items[n.Name] = &n
+	delete(dir.dirs, n.Name)
+}
+
+// current returns a copy of the currently tracked items. It returns nil if no
+// item has been completed yet.
+func (t *checkpointTracker) current() *checkpointDir {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var copyDir func(dir *checkpointDir) (*checkpointDir, bool)
+	copyDir = func(dir *checkpointDir) (*checkpointDir, bool) {
+		res := newCheckpointDir(dir.node)
+		for name, node := range dir.items {
+			res.items[name] = node
+		}
+		empty := len(res.items) == 0
+		for name, sub := range dir.dirs {
+			if sub.node == nil {
+				continue
+			}
+			subCopy, subEmpty := copyDir(sub)
+			res.dirs[name] = subCopy
+			empty = empty && subEmpty
+		}
+		return res, empty
+	}
+
+	res, empty := copyDir(t.root)
+	if empty {
+		return nil
+	}
+	return res
+}
+
+// saveCheckpointTree saves the tree for dir and all pending subdirectories
+// and returns the ID of the tree.
+func (arch *Archiver) saveCheckpointTree(ctx context.Context, dir *checkpointDir) (restic.ID, error) {
+	tree := restic.NewTree(len(dir.items) + len(dir.dirs))
+	for _, node := range dir.items {
+		if err := tree.Insert(node); err != nil {
+			return restic.ID{}, err
+		}
+	}
+
+	for _, sub := range dir.dirs {
+		id, err := arch.saveCheckpointTree(ctx, sub)
+		if err != nil {
+			return restic.ID{}, err
+		}
+
+		node := *sub.node
+		node.Subtree = &id
+		if err := tree.Insert(&node); err != nil {
+			return restic.ID{}, err
+		}
+	}
+
+	return restic.SaveTree(ctx, arch.Repo, tree)
+}
+
+// saveCheckpoint writes a snapshot which contains all items completed so far.
+// If no item has been completed yet, a null ID is returned.
+func (arch *Archiver) saveCheckpoint(ctx context.Context, targets []string, opts SnapshotOptions) (restic.ID, error) {
+	dir := arch.checkpoints.current()
+	if dir == nil {
+		return restic.ID{}, nil
+	}
+
+	// make sure that all data referenced by the completed items is stored
+	if err := arch.Repo.FlushPending(ctx); err != nil {
+		return restic.ID{}, err
+	}
+
+	rootTreeID, err := arch.saveCheckpointTree(ctx, dir)
+	if err != nil {
+		return restic.ID{}, err
+	}
+
+	if err := arch.Repo.FlushPending(ctx); err != nil {
+		return restic.ID{}, err
+	}
+
+	sn, err := restic.NewSnapshot(targets, opts.Tags, opts.Hostname, opts.Time)
+	if err != nil {
+		return restic.ID{}, err
+	}
+
+	sn.AddTags([]string{CheckpointTag})
+	sn.ProgramVersion = opts.ProgramVersion
+	sn.Excludes = opts.Excludes
+	sn.Labels = opts.Labels
+	sn.Description = opts.Description
+	if opts.ParentSnapshot != nil {
+		sn.Parent = opts.ParentSnapshot.ID()
+	}
+	sn.Tree = &rootTreeID
+
+	// The backend may store the snapshot although saving it is reported as
+	// canceled, which would leave an untracked checkpoint behind. Thus finish
+	// writing the small snapshot file even if the backup is aborted.
+	return restic.SaveSnapshot(context.Background(), arch.Repo, sn)
+}
+
+// runCheckpoints writes a checkpoint snapshot every CheckpointInterval until
+// done is closed. Each checkpoint replaces the previous one.
+func (arch *Archiver) runCheckpoints(ctx context.Context, done <-chan struct{}, targets []string, opts SnapshotOptions) error {
+	ticker := time.NewTicker(arch.CheckpointInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+
+		id, err := arch.saveCheckpoint(ctx, targets, opts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "checkpoint")
+		}
+		if id.IsNull() {
+			continue
+		}
+		debug.Log("saved checkpoint snapshot %v", id)
+
+		// the previous checkpoint is superseded even if the backup has been
+		// aborted in the meantime
+		arch.removeCheckpoint(context.Background())
+		arch.checkpointID = id
+	}
+}
+
+// removeCheckpoint removes the last checkpoint snapshot, if any.
+func (arch *Archiver) removeCheckpoint(ctx context.Context) {
+	if arch.checkpointID.IsNull() {
+		return
+	}
+
+	if arch.removeCheckpointSnapshot(ctx, arch.checkpointID) {
+		arch.checkpointID = restic.ID{}
+	}
+}
+
+// removeStaleCheckpoints removes the checkpoint snapshots which interrupted
+// backups of the same paths on the same host have left behind. They are
+// superseded by the final snapshot sn of this backup. As the checkpoints of
+// concurrent backups cannot be told apart from stale ones, nothing is removed
+// while another process holds a lock on the repository.
+func (arch *Archiver) removeStaleCheckpoints(ctx context.Context, sn *restic.Snapshot) {
+	var stale restic.IDs
+	err := restic.ForAllSnapshots(ctx, arch.Repo, arch.Repo, nil, func(id restic.ID, other *restic.Snapshot, err error) error {
+		if err != nil {
+			debug.Log("unable to load snapshot %v: %v", id.Str(), err)
+			return nil
+		}
+
+		if other.HasTags([]string{CheckpointTag}) && other.Hostname == sn.Hostname &&
+			other.HasPaths(sn.Paths) && sn.HasPaths(other.Paths) {
+			stale = append(stale, id)
+		}
+		return nil
+	})
+	if err != nil {
+		arch.warn("unable to list checkpoint snapshots: %v\n", err)
+		return
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	// A backup which was running when the snapshots were listed still holds
+	// its lock, checkpoints of backups started afterwards were not listed.
+	locked, err := lockedByOtherProcess(ctx, arch.Repo)
+	if err != nil {
+		arch.warn("unable to list locks: %v\n", err)
+		return
+	}
+	if locked {
+		debug.Log("repository is locked by another process, keeping %d checkpoints", len(stale))
+		return
+	}
+
+	for _, id := range stale {
+		arch.removeCheckpointSnapshot(ctx, id)
+	}
+}
+
+// lockedByOtherProcess returns whether a process other than the current one
+// holds a lock on the repository which is not stale. Locks which cannot be
+// loaded are assumed to be held.
+func lockedByOtherProcess(ctx context.Context, repo restic.Repository) (bool, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return false, err
+	}
+
+	locked := false
+	err = restic.ForAllLocks(ctx, repo, nil, func(id restic.ID, lock *restic.Lock, err error) error {
+		if err != nil {
+			debug.Log("unable to load lock %v: %v", id.Str(), err)
+			locked = true
+			return nil
+		}
+
+		if lock.Hostname == hostname && lock.PID == os.Getpid() {
+			return nil
+		}
+		if !lock.Stale() {
+			debug.Log("repository is locked by %v", lock)
+			locked = true
+		}
+		return nil
+	})
+	return locked, err
+}
+
+// removeCheckpointSnapshot removes the checkpoint snapshot id. It returns
+// false if the snapshot could not be removed, which is only reported as a
+// warning as the snapshot of the backup is not affected.
+func (arch *Archiver) removeCheckpointSnapshot(ctx context.Context, id restic.ID) bool {
+	h := backend.Handle{Type: restic.SnapshotFile, Name: id.String()}
+	if err := arch.Repo.Backend().Remove(ctx, h); err != nil {
+		arch.warn("unable to remove checkpoint snapshot %v: %v\n", id.Str(), err)
+		return false
+	}
+	debug.Log("removed checkpoint snapshot %v", id)
+	return true
+}
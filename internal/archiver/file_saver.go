@@ -3,6 +3,7 @@ package archiver
 import (
 	"context"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"sync"
@@ -30,6 +31,19 @@ type FileSaver struct {
 	CompleteBlob func(bytes uint64)
 
 	NodeFromFileInfo func(snPath, filename string, fi os.FileInfo) (*restic.Node, error)
+
+	// RetryChangedFiles is the number of times a file is read again if it
+	// has been modified while reading it.
+	RetryChangedFiles uint
+
+	// FileHash is the name of the algorithm used to compute a checksum of
+	// the whole file content, which is stored in the node. If empty, no
+	// checksum is computed.
+	FileHash string
+
+	// hardlinks tracks files with several hard links, so their content is
+	// only read once. If nil, each link is read.
+	hardlinks *hardlinkIndex
 }
 
 // NewFileSaver returns a new file saver. A worker pool with fileWorkers is
@@ -119,6 +133,10 @@ func (s *FileSaver) saveFile(ctx context.Context, chnker *chunker.Chunker, snPat
 	var lock sync.Mutex
 	remaining := 0
 	isCompleted := false
+	// blobs added to the repository while reading the file. If the file is
+	// read several times, only those referenced by the final content are
+	// counted in the stats.
+	added := make(map[restic.ID]ItemStats)
 
 	completeBlob := func() {
 		lock.Lock()
@@ -133,6 +151,10 @@ func (s *FileSaver) saveFile(ctx context.Context, chnker *chunker.Chunker, snPat
 				if id.IsNull() {
 					panic("completed file with null ID")
 				}
+				if stats, ok := added[id]; ok {
+					fnr.stats.Add(stats)
+					delete(added, id)
+				}
 			}
 			isCompleted = true
 			finish(fnr)
@@ -169,65 +191,172 @@ func (s *FileSaver) saveFile(ctx context.Context, chnker *chunker.Chunker, snPat
 		return
 	}
 
-	// reuse the chunker
-	chnker.Reset(f, s.pol)
+	if s.hardlinks != nil && node.Links > 1 && node.Inode != 0 {
+		entry, first := s.hardlinks.add(node)
+		if first {
+			// make the content available to the other links once saved
+			saveFinish := finish
+			finish = func(res futureNodeResult) {
+				s.hardlinks.complete(entry, res.node)
+				saveFinish(res)
+			}
+		} else {
+			select {
+			case <-entry.done:
+			case <-ctx.Done():
+				_ = f.Close()
+				completeError(ctx.Err())
+				return
+			}
 
-	node.Content = []restic.ID{}
-	node.Size = 0
-	var idx int
-	for {
-		buf := s.saveFilePool.Get()
-		chunk, err := chnker.Next(buf.Data)
-		if err == io.EOF {
-			buf.Release()
-			break
-		}
+			// if saving the first link failed or the file was modified in
+			// the meantime, fall back to reading the file
+			if entry.reusable(node) {
+				debug.Log("%v is a hard link to an already saved file, reusing its content", snPath)
+				err = f.Close()
+				if err != nil {
+					completeError(err)
+					return
+				}
 
-		buf.Data = chunk.Data
-		node.Size += uint64(chunk.Length)
+				node.Content = entry.node.Content
+				node.FileHash = entry.node.FileHash
+				s.CompleteBlob(node.Size)
+
+				fnr.node = node
+				lock.Lock()
+				remaining++
+				lock.Unlock()
+				finishReading()
+				completeBlob()
+				return
+			}
+		}
+	}
 
+	var fileHash hash.Hash
+	if s.FileHash != "" {
+		fileHash, err = restic.NewFileHash(s.FileHash)
 		if err != nil {
 			_ = f.Close()
 			completeError(err)
 			return
 		}
-		// test if the context has been cancelled, return the error
-		if ctx.Err() != nil {
-			_ = f.Close()
-			completeError(ctx.Err())
-			return
+	}
+
+	// readContent reads the file and saves its chunks in node.Content. It
+	// returns the number of blobs which have been passed to saveBlob.
+	readContent := func(node *restic.Node) (int, error) {
+		// reuse the chunker
+		chnker.Reset(f, s.pol)
+		if fileHash != nil {
+			fileHash.Reset()
 		}
 
-		// add a place to store the saveBlob result
-		pos := idx
+		node.Content = []restic.ID{}
+		node.Size = 0
+		var idx int
+		for {
+			buf := s.saveFilePool.Get()
+			chunk, err := chnker.Next(buf.Data)
+			if err == io.EOF {
+				buf.Release()
+				break
+			}
 
-		lock.Lock()
-		node.Content = append(node.Content, restic.ID{})
-		lock.Unlock()
+			buf.Data = chunk.Data
+			node.Size += uint64(chunk.Length)
 
-		s.saveBlob(ctx, restic.DataBlob, buf, target, func(sbr SaveBlobResponse) {
-			lock.Lock()
-			if !sbr.known {
-				fnr.stats.DataBlobs++
-				fnr.stats.DataSize += uint64(sbr.length)
-				fnr.stats.DataSizeInRepo += uint64(sbr.sizeInRepo)
+			if err != nil {
+				return idx, err
+			}
+			if fileHash != nil {
+				_, _ = fileHash.Write(chunk.Data)
 			}
+			// test if the context has been cancelled, return the error
+			if ctx.Err() != nil {
+				return idx, ctx.Err()
+			}
+
+			// add a place to store the saveBlob result
+			pos := idx
 
-			node.Content[pos] = sbr.id
+			lock.Lock()
+			node.Content = append(node.Content, restic.ID{})
 			lock.Unlock()
 
-			completeBlob()
-		})
-		idx++
+			s.saveBlob(ctx, restic.DataBlob, buf, target, func(sbr SaveBlobResponse) {
+				lock.Lock()
+				if !sbr.known {
+					added[sbr.id] = ItemStats{
+						DataBlobs:      1,
+						DataSize:       uint64(sbr.length),
+						DataSizeInRepo: uint64(sbr.sizeInRepo),
+					}
+				}
+
+				node.Content[pos] = sbr.id
+				lock.Unlock()
 
-		// test if the context has been cancelled, return the error
-		if ctx.Err() != nil {
+				completeBlob()
+			})
+			idx++
+
+			// test if the context has been cancelled, return the error
+			if ctx.Err() != nil {
+				return idx, ctx.Err()
+			}
+
+			s.CompleteBlob(uint64(len(chunk.Data)))
+		}
+
+		if fileHash != nil {
+			node.FileHash = restic.FormatFileHash(s.FileHash, fileHash.Sum(nil))
+		}
+
+		return idx, nil
+	}
+
+	// blobs counts the blobs of all attempts to read the file
+	var blobs int
+	for attempt := uint(0); ; attempt++ {
+		before := *node
+		idx, err := readContent(node)
+		blobs += idx
+		if err != nil {
 			_ = f.Close()
-			completeError(ctx.Err())
+			completeError(err)
 			return
 		}
 
-		s.CompleteBlob(uint64(len(chunk.Data)))
+		if s.RetryChangedFiles == 0 {
+			break
+		}
+
+		after, err := s.statAgain(snPath, f)
+		if err != nil {
+			_ = f.Close()
+			completeError(err)
+			return
+		}
+
+		if !changedWhileReading(&before, after) {
+			break
+		}
+
+		if attempt == s.RetryChangedFiles {
+			debug.Log("%v still changed after %d retries", snPath, attempt)
+			node.ChangedDuringBackup = true
+			break
+		}
+
+		debug.Log("%v changed while reading, retrying", snPath)
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			_ = f.Close()
+			completeError(err)
+			return
+		}
+		node = after
 	}
 
 	err = f.Close()
@@ -240,12 +369,30 @@ func (s *FileSaver) saveFile(ctx context.Context, chnker *chunker.Chunker, snPat
 	lock.Lock()
 	// require one additional completeFuture() call to ensure that the future only completes
 	// after reaching the end of this method
-	remaining += idx + 1
+	remaining += blobs + 1
 	lock.Unlock()
 	finishReading()
 	completeBlob()
 }
 
+// statAgain returns a new node for the already opened file f.
+func (s *FileSaver) statAgain(snPath string, f fs.File) (*restic.Node, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.NodeFromFileInfo(snPath, f.Name(), fi)
+}
+
+// changedWhileReading returns true if the size, modification or change time of
+// the file differ between the nodes created before and after reading it.
+func changedWhileReading(before, after *restic.Node) bool {
+	return before.Size != after.Size ||
+		!before.ModTime.Equal(after.ModTime) ||
+		!before.ChangeTime.Equal(after.ChangeTime)
+}
+
 func (s *FileSaver) worker(ctx context.Context, jobs <-chan saveFileJob) {
 	// a worker has one chunker which is reused for each file (because it contains a rather large buffer)
 	chnker := chunker.New(nil, s.pol)
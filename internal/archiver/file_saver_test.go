@@ -2,6 +2,7 @@ package archiver
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -50,7 +51,7 @@ func startFileSaver(ctx context.Context, t testing.TB) (*FileSaver, context.Cont
 
 	s := NewFileSaver(ctx, wg, saveBlob, pol, workers, workers)
 	s.NodeFromFileInfo = func(snPath, filename string, fi os.FileInfo) (*restic.Node, error) {
-		return restic.NodeFromFileInfo(filename, fi)
+		return restic.NodeFromFileInfo(filename, fi, nil, false)
 	}
 
 	return s, ctx, wg
@@ -100,3 +101,146 @@ func TestFileSaver(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// changingFile appends data to the file on disk during the first read after
+// opening or seeking, until changes reaches zero.
+type changingFile struct {
+	fs.File
+	changes  int
+	modified bool
+}
+
+func (f *changingFile) Read(p []byte) (int, error) {
+	if !f.modified && f.changes > 0 {
+		f.modified = true
+		f.changes--
+
+		wr, err := os.OpenFile(f.Name(), os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return 0, err
+		}
+		_, err = wr.Write([]byte("more data"))
+		if err != nil {
+			_ = wr.Close()
+			return 0, err
+		}
+		if err := wr.Close(); err != nil {
+			return 0, err
+		}
+	}
+	return f.File.Read(p)
+}
+
+func (f *changingFile) Seek(offset int64, whence int) (int64, error) {
+	f.modified = false
+	return f.File.Seek(offset, whence)
+}
+
+func TestFileSaverRetryChangedFiles(t *testing.T) {
+	for _, tc := range []struct {
+		retries uint
+		changes int
+		marked  bool
+	}{
+		{0, 1, false},
+		{1, 1, false},
+		{2, 2, false},
+		{2, 3, true},
+	} {
+		t.Run(fmt.Sprintf("retries-%d-changes-%d", tc.retries, tc.changes), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			filename := createTestFiles(t, 1)[0]
+
+			s, ctx, wg := startFileSaver(ctx, t)
+			s.RetryChangedFiles = tc.retries
+
+			f, err := fs.Local{}.Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fi, err := f.Stat()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file := &changingFile{File: f, changes: tc.changes}
+			fn := s.Save(ctx, filename, filename, file, fi, func() {}, func() {}, func(*restic.Node, ItemStats) {})
+			fnr := fn.take(ctx)
+			if fnr.err != nil {
+				t.Fatalf("unable to save file: %v", fnr.err)
+			}
+
+			if fnr.node.ChangedDuringBackup != tc.marked {
+				t.Errorf("wrong value for ChangedDuringBackup, want %v, got %v", tc.marked, fnr.node.ChangedDuringBackup)
+			}
+
+			// blobs of discarded attempts must not be counted
+			if fnr.stats.DataBlobs != 1 || fnr.stats.DataSize != fnr.node.Size {
+				t.Errorf("wrong stats, want 1 blob with %v bytes, got %+v", fnr.node.Size, fnr.stats)
+			}
+
+			if tc.changes <= int(tc.retries) {
+				data, err := os.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if fnr.node.Size != uint64(len(data)) {
+					t.Errorf("wrong size, want %v, got %v", len(data), fnr.node.Size)
+				}
+			}
+
+			s.TriggerShutdown()
+			if err := wg.Wait(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestFileSaverFileHash(t *testing.T) {
+	for _, changes := range []int{0, 1} {
+		t.Run(fmt.Sprintf("changes-%d", changes), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			filename := createTestFiles(t, 1)[0]
+
+			s, ctx, wg := startFileSaver(ctx, t)
+			s.RetryChangedFiles = 1
+			s.FileHash = restic.FileHashSHA256
+
+			f, err := fs.Local{}.Open(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fi, err := f.Stat()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			file := &changingFile{File: f, changes: changes}
+			fn := s.Save(ctx, filename, filename, file, fi, func() {}, func() {}, func(*restic.Node, ItemStats) {})
+			fnr := fn.take(ctx)
+			if fnr.err != nil {
+				t.Fatalf("unable to save file: %v", fnr.err)
+			}
+
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(data)
+			want := restic.FormatFileHash(restic.FileHashSHA256, sum[:])
+			if fnr.node.FileHash != want {
+				t.Errorf("wrong file hash, want %v, got %v", want, fnr.node.FileHash)
+			}
+
+			s.TriggerShutdown()
+			if err := wg.Wait(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
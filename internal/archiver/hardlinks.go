@@ -0,0 +1,82 @@
+package archiver
+
+import (
+	"sync"
+
+	"github.com/restic/restic/internal/restic"
+)
+
+// hardlinkKey identifies an inode on a specific device.
+type hardlinkKey struct {
+	inode, device uint64
+}
+
+// hardlinkEntry holds the node of the first link to an inode which has been
+// read during the backup.
+type hardlinkEntry struct {
+	// done is closed once the first link has been saved
+	done chan struct{}
+	// node is nil if saving the first link failed
+	node *restic.Node
+	// remaining is the number of links which have not been seen yet
+	remaining uint64
+}
+
+// hardlinkIndex tracks the content of files with several hard links, such
+// that each file is only read once during a backup.
+type hardlinkIndex struct {
+	mu      sync.Mutex
+	entries map[hardlinkKey]*hardlinkEntry
+}
+
+func newHardlinkIndex() *hardlinkIndex {
+	return &hardlinkIndex{
+		entries: make(map[hardlinkKey]*hardlinkEntry),
+	}
+}
+
+// add records a link to the file described by node. If the file has not been
+// seen before, first is true and the caller must read the file and report the
+// result via complete. Otherwise the returned entry can be used to wait for
+// the content of the first link.
+func (idx *hardlinkIndex) add(node *restic.Node) (entry *hardlinkEntry, first bool) {
+	key := hardlinkKey{node.Inode, node.DeviceID}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.entries[key]
+	if !ok {
+		entry = &hardlinkEntry{
+			done:      make(chan struct{}),
+			remaining: node.Links - 1,
+		}
+		idx.entries[key] = entry
+		return entry, true
+	}
+
+	// forget the inode once all links have been seen
+	entry.remaining--
+	if entry.remaining == 0 {
+		delete(idx.entries, key)
+	}
+	return entry, false
+}
+
+// complete records the node of the first link. node is nil if the file could
+// not be saved, its content must not be reused in that case.
+func (idx *hardlinkIndex) complete(entry *hardlinkEntry, node *restic.Node) {
+	if node != nil && !node.ChangedDuringBackup {
+		n := *node
+		entry.node = &n
+	}
+	close(entry.done)
+}
+
+// reusable returns true if the content of the first link, as stored in
+// entry, can be used for the file described by node.
+func (entry *hardlinkEntry) reusable(node *restic.Node) bool {
+	return entry.node != nil &&
+		entry.node.Size == node.Size &&
+		entry.node.ModTime.Equal(node.ModTime)
+}
@@ -19,6 +19,11 @@ type Scanner struct {
 	Select       SelectFunc
 	Error        ErrorFunc
 	Result       func(item string, s ScanStats)
+
+	// Dereference and DereferenceArgs have the same meaning as for the
+	// Archiver.
+	Dereference     bool
+	DereferenceArgs bool
 }
 
 // NewScanner initializes a new Scanner.
@@ -46,7 +51,7 @@ func (s *Scanner) scanTree(ctx context.Context, stats ScanStats, tree Tree) (Sca
 			return ScanStats{}, err
 		}
 
-		stats, err = s.scan(ctx, stats, abstarget)
+		stats, err = s.scan(ctx, stats, abstarget, s.Dereference || s.DereferenceArgs, nil)
 		if err != nil {
 			return ScanStats{}, err
 		}
@@ -98,7 +103,9 @@ func (s *Scanner) Scan(ctx context.Context, targets []string) error {
 	return nil
 }
 
-func (s *Scanner) scan(ctx context.Context, stats ScanStats, target string) (ScanStats, error) {
+// scan adds the stats for target. If dereference is true, symbolic links are
+// followed unless they point to one of the directories in parents.
+func (s *Scanner) scan(ctx context.Context, stats ScanStats, target string, dereference bool, parents []os.FileInfo) (ScanStats, error) {
 	if ctx.Err() != nil {
 		return stats, nil
 	}
@@ -114,6 +121,15 @@ func (s *Scanner) scan(ctx context.Context, stats ScanStats, target string) (Sca
 		return stats, s.Error(target, err)
 	}
 
+	openFlags := fs.O_NOFOLLOW
+	if dereference && fi.Mode()&os.ModeSymlink != 0 {
+		// errors are reported by the archiver, just count the link
+		if linkFi, err := s.FS.Stat(target); err == nil && !isParent(parents, linkFi) {
+			fi = linkFi
+			openFlags = 0
+		}
+	}
+
 	// run remaining select functions that require file information
 	if !s.Select(target, fi) {
 		return stats, nil
@@ -124,14 +140,15 @@ func (s *Scanner) scan(ctx context.Context, stats ScanStats, target string) (Sca
 		stats.Files++
 		stats.Bytes += uint64(fi.Size())
 	case fi.Mode().IsDir():
-		names, err := readdirnames(s.FS, target, fs.O_NOFOLLOW)
+		names, err := readdirnames(s.FS, target, openFlags)
 		if err != nil {
 			return stats, s.Error(target, err)
 		}
 		sort.Strings(names)
 
+		parents = append(parents, fi)
 		for _, name := range names {
-			stats, err = s.scan(ctx, stats, filepath.Join(target, name))
+			stats, err = s.scan(ctx, stats, filepath.Join(target, name), s.Dereference, parents)
 			if err != nil {
 				return stats, err
 			}
@@ -144,3 +161,15 @@ func (s *Scanner) scan(ctx context.Context, stats ScanStats, target string) (Sca
 	s.Result(target, stats)
 	return stats, nil
 }
+
+// isParent returns true if fi refers to one of the directories in parents.
+// Directories are identified by their device and inode number (or the
+// equivalent on Windows), see os.SameFile.
+func isParent(parents []os.FileInfo, fi os.FileInfo) bool {
+	for _, dir := range parents {
+		if os.SameFile(dir, fi) {
+			return true
+		}
+	}
+	return false
+}
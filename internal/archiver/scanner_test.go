@@ -14,10 +14,11 @@ import (
 
 func TestScanner(t *testing.T) {
 	var tests = []struct {
-		name  string
-		src   TestDir
-		want  map[string]ScanStats
-		selFn SelectFunc
+		name        string
+		src         TestDir
+		want        map[string]ScanStats
+		selFn       SelectFunc
+		dereference bool
 	}{
 		{
 			name: "include-all",
@@ -74,10 +75,32 @@ func TestScanner(t *testing.T) {
 				filepath.FromSlash(""):                    {Files: 2, Dirs: 2, Bytes: 30},
 			},
 		},
+		{
+			name: "dereference",
+			src: TestDir{
+				"work": TestDir{
+					"foo":  TestFile{Content: "foo"},
+					"link": TestSymlink{Target: "foo"},
+					"loop": TestSymlink{Target: "."},
+				},
+			},
+			dereference: true,
+			want: map[string]ScanStats{
+				filepath.FromSlash("work/foo"):  {Files: 1, Bytes: 3},
+				filepath.FromSlash("work/link"): {Files: 2, Bytes: 6},
+				filepath.FromSlash("work/loop"): {Files: 2, Others: 1, Bytes: 6},
+				filepath.FromSlash("work"):      {Files: 2, Dirs: 1, Others: 1, Bytes: 6},
+				filepath.FromSlash(""):          {Files: 2, Dirs: 1, Others: 1, Bytes: 6},
+			},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			if test.dereference && runtime.GOOS == "windows" {
+				t.Skip("symlinks are not supported on Windows")
+			}
+
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
@@ -96,6 +119,7 @@ func TestScanner(t *testing.T) {
 			if test.selFn != nil {
 				sc.Select = test.selFn
 			}
+			sc.Dereference = test.dereference
 
 			results := make(map[string]ScanStats)
 			sc.Result = func(item string, s ScanStats) {
@@ -0,0 +1,575 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// ArchiveEntryInfo contains the metadata of an entry of an Archive file system
+// which cannot be represented by os.FileInfo. It is returned by the Sys()
+// method of the os.FileInfo values returned by Archive.
+type ArchiveEntryInfo struct {
+	UID, GID    uint32
+	User, Group string
+
+	// Inode is a synthetic inode number, hardlinked entries share the same
+	// inode number.
+	Inode uint64
+	Links uint64
+
+	// Device is the device number for block and character devices.
+	Device     uint64
+	LinkTarget string
+
+	AccessTime time.Time
+	ChangeTime time.Time
+
+	// ExtendedAttributes maps the name of an extended attribute to its value.
+	ExtendedAttributes map[string]string
+}
+
+// Archive is a read-only file system which provides the content of a tar or
+// zip archive. The archive is read completely when the file system is
+// created, the file contents are kept in a temporary file until Close is
+// called. The archive is made available below the root directory "/".
+type Archive struct {
+	entries map[string]*archiveEntry
+	spool   *os.File
+}
+
+// statically ensure that Archive implements FS.
+var _ FS = &Archive{}
+
+type archiveEntry struct {
+	name    string
+	mode    os.FileMode
+	modTime time.Time
+	info    ArchiveEntryInfo
+
+	// children contains the names of all entries in a directory
+	children map[string]struct{}
+	// data describes where the content of a regular file can be found, it
+	// is shared between hardlinked entries
+	data *archiveData
+}
+
+type archiveData struct {
+	inode uint64
+	links uint64
+
+	offset, size int64
+	zipFile      *zip.File
+}
+
+// NewTarArchive reads the tar archive from rd and returns a file system
+// representing its content.
+func NewTarArchive(rd io.Reader) (*Archive, error) {
+	a, err := newArchive()
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.readTar(rd)
+	if err != nil {
+		_ = a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// NewZipArchive reads the zip archive from rd and returns a file system
+// representing its content. As the zip format stores its index at the end of
+// the file, the whole archive is written to a temporary file first.
+func NewZipArchive(rd io.Reader) (*Archive, error) {
+	a, err := newArchive()
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.readZip(rd)
+	if err != nil {
+		_ = a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func newArchive() (*Archive, error) {
+	spool, err := os.CreateTemp("", "restic-archive-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	a := &Archive{
+		entries: make(map[string]*archiveEntry),
+		spool:   spool,
+	}
+	a.entries["/"] = &archiveEntry{
+		name:     "/",
+		mode:     os.ModeDir | 0755,
+		modTime:  time.Now(),
+		info:     a.defaultInfo(),
+		children: make(map[string]struct{}),
+	}
+	return a, nil
+}
+
+// Close removes the temporary file used to store the file contents.
+func (a *Archive) Close() error {
+	err := a.spool.Close()
+	rerr := os.Remove(a.spool.Name())
+	if err == nil {
+		err = rerr
+	}
+	return errors.WithStack(err)
+}
+
+// Targets returns the top-level entries of the archive.
+func (a *Archive) Targets() []string {
+	names := a.entries["/"].names()
+	targets := make([]string, 0, len(names))
+	for _, name := range names {
+		targets = append(targets, path.Join("/", name))
+	}
+	return targets
+}
+
+func (a *Archive) defaultInfo() ArchiveEntryInfo {
+	return ArchiveEntryInfo{
+		UID:   uint32(os.Getuid()),
+		GID:   uint32(os.Getgid()),
+		Links: 1,
+	}
+}
+
+// paxXattrPrefix is the prefix of PAX records storing extended attributes.
+const paxXattrPrefix = "SCHILY.xattr."
+
+func (a *Archive) readTar(rd io.Reader) error {
+	tr := tar.NewReader(rd)
+
+	var offset int64
+	var inode uint64
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "reading tar archive")
+		}
+
+		entry := &archiveEntry{
+			mode:    hdr.FileInfo().Mode(),
+			modTime: hdr.ModTime,
+			info: ArchiveEntryInfo{
+				UID:        uint32(hdr.Uid),
+				GID:        uint32(hdr.Gid),
+				User:       hdr.Uname,
+				Group:      hdr.Gname,
+				Links:      1,
+				AccessTime: hdr.AccessTime,
+				ChangeTime: hdr.ChangeTime,
+			},
+		}
+
+		for key, value := range hdr.PAXRecords {
+			if !strings.HasPrefix(key, paxXattrPrefix) {
+				continue
+			}
+			if entry.info.ExtendedAttributes == nil {
+				entry.info.ExtendedAttributes = make(map[string]string)
+			}
+			entry.info.ExtendedAttributes[strings.TrimPrefix(key, paxXattrPrefix)] = value
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeGNUSparse:
+			n, err := io.Copy(a.spool, tr)
+			if err != nil {
+				return errors.Wrapf(err, "reading %v", hdr.Name)
+			}
+
+			inode++
+			entry.data = &archiveData{
+				inode:  inode,
+				links:  1,
+				offset: offset,
+				size:   n,
+			}
+			offset += n
+
+		case tar.TypeLink:
+			target, ok := a.entries[cleanArchivePath(hdr.Linkname)]
+			if !ok || target.data == nil || !target.mode.IsRegular() {
+				return errors.Errorf("hardlink %v: target %v not found in archive", hdr.Name, hdr.Linkname)
+			}
+			entry.mode = target.mode
+			entry.data = target.data
+			entry.data.links++
+
+		case tar.TypeSymlink:
+			entry.info.LinkTarget = hdr.Linkname
+
+		case tar.TypeChar, tar.TypeBlock:
+			entry.info.Device = mkdev(hdr.Devmajor, hdr.Devminor)
+
+		case tar.TypeDir, tar.TypeFifo:
+
+		default:
+			// skip unsupported entries, e.g. sockets
+			continue
+		}
+
+		err = a.add(hdr.Name, entry)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Archive) readZip(rd io.Reader) error {
+	size, err := io.Copy(a.spool, rd)
+	if err != nil {
+		return errors.Wrap(err, "reading zip archive")
+	}
+
+	zr, err := zip.NewReader(a.spool, size)
+	if err != nil {
+		return errors.Wrap(err, "reading zip archive")
+	}
+
+	var inode uint64
+	for _, f := range zr.File {
+		entry := &archiveEntry{
+			mode:    f.Mode(),
+			modTime: f.Modified,
+			info:    a.defaultInfo(),
+		}
+
+		switch {
+		case entry.mode.IsDir():
+		case entry.mode.IsRegular():
+			inode++
+			entry.data = &archiveData{
+				inode:   inode,
+				links:   1,
+				size:    int64(f.UncompressedSize64),
+				zipFile: f,
+			}
+
+		case entry.mode&os.ModeSymlink != 0:
+			// the zip format stores the link target as the file content
+			target, err := readZipFile(f)
+			if err != nil {
+				return errors.Wrapf(err, "reading %v", f.Name)
+			}
+			entry.info.LinkTarget = target
+
+		default:
+			// skip unsupported entries
+			continue
+		}
+
+		err = a.add(f.Name, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readZipFile(f *zip.File) (string, error) {
+	rd, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+
+	buf, err := io.ReadAll(rd)
+	if err != nil {
+		_ = rd.Close()
+		return "", err
+	}
+
+	return string(buf), rd.Close()
+}
+
+// cleanArchivePath converts the name of an archive entry to an absolute path.
+func cleanArchivePath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// add inserts the entry into the file system and creates missing parent
+// directories. Entries which occur more than once in an archive replace the
+// earlier ones.
+func (a *Archive) add(name string, entry *archiveEntry) error {
+	p := cleanArchivePath(name)
+	if p == "/" {
+		// the root directory of the archive, e.g. "./"
+		if entry.mode.IsDir() {
+			root := a.entries["/"]
+			root.modTime = entry.modTime
+			root.mode = entry.mode
+			root.info = entry.info
+		}
+		return nil
+	}
+
+	entry.name = path.Base(p)
+	if old, ok := a.entries[p]; ok {
+		if old.mode.IsDir() != entry.mode.IsDir() {
+			return errors.Errorf("archive entry %v occurs both as directory and non-directory", name)
+		}
+		// keep the content of an existing directory
+		entry.children = old.children
+	}
+
+	if entry.mode.IsDir() && entry.children == nil {
+		entry.children = make(map[string]struct{})
+	}
+
+	parent, err := a.mkdirAll(path.Dir(p), entry.modTime)
+	if err != nil {
+		return err
+	}
+	parent.children[entry.name] = struct{}{}
+	a.entries[p] = entry
+
+	return nil
+}
+
+// mkdirAll returns the directory entry for p, missing directories are created.
+func (a *Archive) mkdirAll(p string, modTime time.Time) (*archiveEntry, error) {
+	if entry, ok := a.entries[p]; ok {
+		if !entry.mode.IsDir() {
+			return nil, errors.Errorf("archive entry %v is not a directory", p)
+		}
+		return entry, nil
+	}
+
+	parent, err := a.mkdirAll(path.Dir(p), modTime)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &archiveEntry{
+		name:     path.Base(p),
+		mode:     os.ModeDir | 0755,
+		modTime:  modTime,
+		info:     a.defaultInfo(),
+		children: make(map[string]struct{}),
+	}
+	parent.children[entry.name] = struct{}{}
+	a.entries[p] = entry
+
+	return entry, nil
+}
+
+// mkdev returns the device number for the given major and minor numbers,
+// using the encoding of Linux and glibc.
+func mkdev(major, minor int64) uint64 {
+	dev := (uint64(major) & 0x00000fff) << 8
+	dev |= (uint64(major) & 0xfffff000) << 32
+	dev |= (uint64(minor) & 0x000000ff) << 0
+	dev |= (uint64(minor) & 0xffffff00) << 12
+	return dev
+}
+
+func (e *archiveEntry) names() []string {
+	names := make([]string, 0, len(e.children))
+	for name := range e.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (e *archiveEntry) fileInfo() archiveFileInfo {
+	fi := archiveFileInfo{
+		name:    e.name,
+		mode:    e.mode,
+		modTime: e.modTime,
+		info:    e.info,
+	}
+	if e.data != nil {
+		fi.size = e.data.size
+		fi.info.Inode = e.data.inode
+		fi.info.Links = e.data.links
+	}
+	return fi
+}
+
+func (a *Archive) lookup(op, name string) (*archiveEntry, error) {
+	entry, ok := a.entries[path.Clean(name)]
+	if !ok {
+		return nil, pathError(op, name, os.ErrNotExist)
+	}
+	return entry, nil
+}
+
+// VolumeName returns leading volume name, for the Archive file system it's
+// always the empty string.
+func (a *Archive) VolumeName(_ string) string {
+	return ""
+}
+
+// Open opens a file for reading.
+func (a *Archive) Open(name string) (File, error) {
+	return a.OpenFile(name, O_RDONLY, 0)
+}
+
+// OpenFile opens the named file for reading, the file system is read-only.
+// If there is an error, it will be of type *os.PathError.
+func (a *Archive) OpenFile(name string, flag int, _ os.FileMode) (File, error) {
+	if flag & ^(O_RDONLY|O_NOFOLLOW) != 0 {
+		return nil, pathError("open", name,
+			fmt.Errorf("invalid combination of flags 0x%x", flag))
+	}
+
+	entry, err := a.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi := entry.fileInfo()
+	f := fakeFile{
+		name:     name,
+		FileInfo: fi,
+	}
+
+	switch {
+	case entry.mode.IsDir():
+		entries := make([]os.FileInfo, 0, len(entry.children))
+		for _, child := range entry.names() {
+			entries = append(entries, a.entries[path.Join(path.Clean(name), child)].fileInfo())
+		}
+		return fakeDir{
+			entries:  entries,
+			fakeFile: f,
+		}, nil
+
+	case entry.mode.IsRegular():
+		var rd io.ReadCloser
+		if entry.data.zipFile != nil {
+			rd, err = entry.data.zipFile.Open()
+			if err != nil {
+				return nil, pathError("open", name, err)
+			}
+		} else {
+			rd = io.NopCloser(io.NewSectionReader(a.spool, entry.data.offset, entry.data.size))
+		}
+		return &readerFile{
+			ReadCloser:     rd,
+			AllowEmptyFile: true,
+			fakeFile:       f,
+		}, nil
+	}
+
+	if flag&O_NOFOLLOW != 0 && entry.mode&os.ModeSymlink != 0 {
+		return nil, pathError("open", name, syscall.ELOOP)
+	}
+
+	return f, nil
+}
+
+// Stat returns a FileInfo describing the named file. Symlinks are not
+// resolved. If there is an error, it will be of type *os.PathError.
+func (a *Archive) Stat(name string) (os.FileInfo, error) {
+	return a.Lstat(name)
+}
+
+// Lstat returns the FileInfo structure describing the named file. The Sys()
+// method of the returned FileInfo returns an *ArchiveEntryInfo. If there is
+// an error, it will be of type *os.PathError.
+func (a *Archive) Lstat(name string) (os.FileInfo, error) {
+	entry, err := a.lookup("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.fileInfo(), nil
+}
+
+// Join joins any number of path elements into a single path, adding a
+// Separator if necessary.
+func (a *Archive) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+// Separator returns the OS and FS dependent separator for dirs/subdirs/files.
+func (a *Archive) Separator() string {
+	return "/"
+}
+
+// IsAbs reports whether the path is absolute. For the Archive, this is always the case.
+func (a *Archive) IsAbs(_ string) bool {
+	return true
+}
+
+// Abs returns an absolute representation of path. For the Archive, all paths
+// are absolute.
+func (a *Archive) Abs(p string) (string, error) {
+	return path.Clean(p), nil
+}
+
+// Clean returns the cleaned path. For details, see filepath.Clean.
+func (a *Archive) Clean(p string) string {
+	return path.Clean(p)
+}
+
+// Base returns the last element of p.
+func (a *Archive) Base(p string) string {
+	return path.Base(p)
+}
+
+// Dir returns p without the last element.
+func (a *Archive) Dir(p string) string {
+	return path.Dir(p)
+}
+
+// archiveFileInfo implements os.FileInfo for entries of an Archive.
+type archiveFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+	info    ArchiveEntryInfo
+}
+
+func (fi archiveFileInfo) Name() string {
+	return fi.name
+}
+
+func (fi archiveFileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi archiveFileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+func (fi archiveFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi archiveFileInfo) IsDir() bool {
+	return fi.mode.IsDir()
+}
+
+func (fi archiveFileInfo) Sys() interface{} {
+	info := fi.info
+	return &info
+}
@@ -0,0 +1,141 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func newTestTar(t testing.TB) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	mtime := time.Unix(1700000000, 0)
+
+	for _, hdr := range []*tar.Header{
+		{Typeflag: tar.TypeDir, Name: "./", Mode: 0700, ModTime: mtime},
+		{Typeflag: tar.TypeReg, Name: "./dir/file", Mode: 0640, Size: 3, Uid: 1000, Gid: 100, Uname: "user", Gname: "users", ModTime: mtime,
+			PAXRecords: map[string]string{"SCHILY.xattr.user.foo": "bar"}},
+		{Typeflag: tar.TypeLink, Name: "./dir/hardlink", Linkname: "./dir/file", ModTime: mtime},
+		{Typeflag: tar.TypeSymlink, Name: "./link", Linkname: "dir/file", Mode: 0777, ModTime: mtime},
+		{Typeflag: tar.TypeReg, Name: "./other", Mode: 0644, Size: 5, ModTime: mtime},
+	} {
+		rtest.OK(t, w.WriteHeader(hdr))
+		switch hdr.Name {
+		case "./dir/file":
+			_, err := w.Write([]byte("foo"))
+			rtest.OK(t, err)
+		case "./other":
+			_, err := w.Write([]byte("other"))
+			rtest.OK(t, err)
+		}
+	}
+	rtest.OK(t, w.Close())
+
+	return buf
+}
+
+func TestArchiveTar(t *testing.T) {
+	fs, err := NewTarArchive(newTestTar(t))
+	rtest.OK(t, err)
+	defer func() {
+		rtest.OK(t, fs.Close())
+	}()
+
+	rtest.Equals(t, []string{"/dir", "/link", "/other"}, fs.Targets())
+
+	verifyDirectoryContents(t, fs, "/", []string{"dir", "link", "other"})
+	verifyDirectoryContents(t, fs, "/dir", []string{"file", "hardlink"})
+	verifyFileContentOpen(t, fs, "/dir/file", []byte("foo"))
+	verifyFileContentOpenFile(t, fs, "/dir/hardlink", []byte("foo"))
+	verifyFileContentOpen(t, fs, "/other", []byte("other"))
+
+	fi, err := fs.Lstat("/dir/file")
+	rtest.OK(t, err)
+	rtest.Equals(t, os.FileMode(0640), fi.Mode())
+	rtest.Equals(t, int64(3), fi.Size())
+	info := fi.Sys().(*ArchiveEntryInfo)
+	rtest.Equals(t, uint32(1000), info.UID)
+	rtest.Equals(t, "users", info.Group)
+	rtest.Equals(t, uint64(2), info.Links)
+	rtest.Equals(t, map[string]string{"user.foo": "bar"}, info.ExtendedAttributes)
+
+	fi, err = fs.Lstat("/dir/hardlink")
+	rtest.OK(t, err)
+	rtest.Equals(t, info.Inode, fi.Sys().(*ArchiveEntryInfo).Inode)
+
+	fi, err = fs.Lstat("/link")
+	rtest.OK(t, err)
+	rtest.Assert(t, fi.Mode()&os.ModeSymlink != 0, "wrong mode for symlink: %v", fi.Mode())
+	rtest.Equals(t, "dir/file", fi.Sys().(*ArchiveEntryInfo).LinkTarget)
+
+	fi, err = fs.Lstat("/")
+	rtest.OK(t, err)
+	rtest.Equals(t, os.ModeDir|0700, fi.Mode())
+
+	// implicitly created directory
+	fi, err = fs.Lstat("/dir")
+	rtest.OK(t, err)
+	rtest.Assert(t, fi.IsDir(), "/dir is not a directory")
+
+	_, err = fs.Lstat("/missing")
+	rtest.Assert(t, os.IsNotExist(err), "unexpected error %v", err)
+}
+
+func TestArchiveTarInvalidHardlink(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	rtest.OK(t, w.WriteHeader(&tar.Header{Typeflag: tar.TypeLink, Name: "link", Linkname: "missing"}))
+	rtest.OK(t, w.Close())
+
+	_, err := NewTarArchive(buf)
+	rtest.Assert(t, err != nil, "missing error for invalid hardlink")
+}
+
+func TestArchiveZip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	mtime := time.Unix(1700000000, 0)
+
+	for _, entry := range []struct {
+		name    string
+		mode    os.FileMode
+		content string
+	}{
+		{"dir/", os.ModeDir | 0755, ""},
+		{"dir/file", 0600, "foobar"},
+		{"link", os.ModeSymlink | 0777, "dir/file"},
+	} {
+		hdr := &zip.FileHeader{Name: entry.name, Modified: mtime, Method: zip.Deflate}
+		hdr.SetMode(entry.mode)
+		fw, err := w.CreateHeader(hdr)
+		rtest.OK(t, err)
+		_, err = fw.Write([]byte(entry.content))
+		rtest.OK(t, err)
+	}
+	rtest.OK(t, w.Close())
+
+	fs, err := NewZipArchive(buf)
+	rtest.OK(t, err)
+	defer func() {
+		rtest.OK(t, fs.Close())
+	}()
+
+	rtest.Equals(t, []string{"/dir", "/link"}, fs.Targets())
+	verifyDirectoryContents(t, fs, "/dir", []string{"file"})
+	verifyFileContentOpen(t, fs, "/dir/file", []byte("foobar"))
+
+	fi, err := fs.Lstat("/dir/file")
+	rtest.OK(t, err)
+	rtest.Equals(t, os.FileMode(0600), fi.Mode())
+	rtest.Equals(t, int64(6), fi.Size())
+	rtest.Assert(t, fi.ModTime().Equal(mtime), "wrong mtime %v", fi.ModTime())
+
+	fi, err = fs.Lstat("/link")
+	rtest.OK(t, err)
+	rtest.Equals(t, "dir/file", fi.Sys().(*ArchiveEntryInfo).LinkTarget)
+}
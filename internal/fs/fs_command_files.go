@@ -0,0 +1,207 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// CommandFiles is a wrapper around a file system which adds virtual files
+// containing the standard output of commands. A command is only started once
+// its file is read, so the number of commands running concurrently is limited
+// by the number of files which are read concurrently. If a command fails,
+// reading its file returns a regular (non-fatal) error, such that the backup
+// continues with the other files.
+type CommandFiles struct {
+	FS
+
+	ctx       context.Context
+	modTime   time.Time
+	logOutput io.Writer
+
+	files map[string]*commandFile
+	dirs  map[string]struct{}
+}
+
+type commandFile struct {
+	args []string
+	fi   os.FileInfo
+}
+
+// statically ensure that CommandFiles implements FS.
+var _ FS = &CommandFiles{}
+
+// NewCommandFiles returns a wrapper around fs. The output on stderr of the
+// commands is written to logOutput, the modification time of the virtual
+// files is modTime. The commands are cancelled when ctx is cancelled.
+func NewCommandFiles(ctx context.Context, fs FS, modTime time.Time, logOutput io.Writer) *CommandFiles {
+	return &CommandFiles{
+		FS:        fs,
+		ctx:       ctx,
+		modTime:   modTime,
+		logOutput: logOutput,
+		files:     make(map[string]*commandFile),
+		dirs:      make(map[string]struct{}),
+	}
+}
+
+// Add adds a virtual file at the absolute path target, which contains the
+// output of the command args.
+func (fs *CommandFiles) Add(target string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("command is empty")
+	}
+
+	target = fs.Clean(target)
+	if target == fs.Dir(target) {
+		return errors.Errorf("invalid filename %v", target)
+	}
+	if _, ok := fs.files[target]; ok {
+		return errors.Errorf("duplicate filename %v", target)
+	}
+	if _, ok := fs.dirs[target]; ok {
+		return errors.Errorf("%v is already used as a directory", target)
+	}
+	for dir := fs.Dir(target); ; dir = fs.Dir(dir) {
+		if _, ok := fs.files[dir]; ok {
+			return errors.Errorf("%v is already used as a file", dir)
+		}
+		if dir == fs.Dir(dir) {
+			break
+		}
+	}
+
+	fs.files[target] = &commandFile{
+		args: args,
+		fi: fakeFileInfo{
+			name:    fs.Base(target),
+			mode:    0644,
+			modtime: fs.modTime,
+		},
+	}
+
+	// record the parent directories, they are returned by Lstat if they do
+	// not exist in the underlying file system
+	for dir := fs.Dir(target); ; dir = fs.Dir(dir) {
+		fs.dirs[dir] = struct{}{}
+		if dir == fs.Dir(dir) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// Open opens a file for reading.
+func (fs *CommandFiles) Open(name string) (File, error) {
+	return fs.OpenFile(name, O_RDONLY, 0)
+}
+
+// OpenFile opens a file. Virtual files can only be opened for reading.
+func (fs *CommandFiles) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	cf, ok := fs.files[fs.Clean(name)]
+	if !ok {
+		return fs.FS.OpenFile(name, flag, perm)
+	}
+
+	if flag & ^(O_RDONLY|O_NOFOLLOW) != 0 {
+		return nil, pathError("open", name,
+			fmt.Errorf("invalid combination of flags 0x%x", flag))
+	}
+
+	rd := &lazyCommandReader{
+		ctx:       fs.ctx,
+		args:      cf.args,
+		logOutput: fs.logOutput,
+	}
+	return newReaderFile(rd, cf.fi, false), nil
+}
+
+// Stat returns a FileInfo describing the named file.
+func (fs *CommandFiles) Stat(name string) (os.FileInfo, error) {
+	if fi, ok := fs.virtualFileInfo(name); ok {
+		return fi, nil
+	}
+	return fs.FS.Stat(name)
+}
+
+// Lstat returns the FileInfo structure describing the named file.
+func (fs *CommandFiles) Lstat(name string) (os.FileInfo, error) {
+	if fi, ok := fs.virtualFileInfo(name); ok {
+		return fi, nil
+	}
+	return fs.FS.Lstat(name)
+}
+
+// virtualFileInfo returns the FileInfo of a virtual file or of a parent
+// directory of a virtual file which does not exist in the underlying file
+// system.
+func (fs *CommandFiles) virtualFileInfo(name string) (os.FileInfo, bool) {
+	name = fs.Clean(name)
+	if cf, ok := fs.files[name]; ok {
+		return cf.fi, true
+	}
+
+	if _, ok := fs.dirs[name]; ok {
+		if _, err := fs.FS.Lstat(name); errors.Is(err, os.ErrNotExist) {
+			return fakeFileInfo{
+				name:    fs.Base(name),
+				mode:    os.ModeDir | 0755,
+				modtime: fs.modTime,
+			}, true
+		}
+	}
+
+	return nil, false
+}
+
+// lazyCommandReader starts the command on the first call to Read.
+type lazyCommandReader struct {
+	ctx       context.Context
+	args      []string
+	logOutput io.Writer
+
+	rd *CommandReader
+}
+
+func (r *lazyCommandReader) Read(p []byte) (int, error) {
+	if r.rd == nil {
+		rd, err := NewCommandReader(r.ctx, r.args, r.logOutput)
+		if err != nil {
+			return 0, r.commandError(err)
+		}
+		r.rd = rd
+	}
+
+	n, err := r.rd.Read(p)
+	if errors.IsFatal(err) {
+		err = r.commandError(err)
+	}
+	return n, err
+}
+
+func (r *lazyCommandReader) Close() error {
+	if r.rd == nil {
+		return nil
+	}
+
+	err := r.rd.Close()
+	if err != nil {
+		err = r.commandError(err)
+	}
+	return err
+}
+
+// commandError returns an error which includes the command, such that it is
+// clear which of the commands failed. Unlike for --stdin-from-command, the
+// error is not fatal: the file is reported as unreadable and the backup
+// continues.
+func (r *lazyCommandReader) commandError(err error) error {
+	msg := strings.TrimPrefix(err.Error(), "Fatal: ")
+	return errors.Errorf("%v: %v", strings.Join(r.args, " "), msg)
+}
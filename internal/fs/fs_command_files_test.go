@@ -0,0 +1,70 @@
+package fs_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/test"
+)
+
+func TestCommandFiles(t *testing.T) {
+	tempdir := test.TempDir(t)
+	modTime := time.Unix(1700000000, 0)
+
+	cfs := fs.NewCommandFiles(context.TODO(), fs.Local{}, modTime, io.Discard)
+	hello := filepath.Join(tempdir, "hello")
+	dump := filepath.Join(tempdir, "dumps", "db")
+	test.OK(t, cfs.Add(hello, []string{"echo", "hello world"}))
+	test.OK(t, cfs.Add(dump, []string{"echo", "dump"}))
+
+	for _, target := range []string{hello, filepath.Join(hello, "foo"), filepath.Join(tempdir, "dumps")} {
+		err := cfs.Add(target, []string{"true"})
+		test.Assert(t, err != nil, "missing error for %v", target)
+	}
+
+	fi, err := cfs.Lstat(hello)
+	test.OK(t, err)
+	test.Assert(t, fi.Mode().IsRegular(), "unexpected mode %v", fi.Mode())
+	test.Equals(t, "hello", fi.Name())
+	test.Equals(t, modTime, fi.ModTime())
+
+	// the parent directory only exists in the wrapper
+	fi, err = cfs.Lstat(filepath.Join(tempdir, "dumps"))
+	test.OK(t, err)
+	test.Assert(t, fi.IsDir(), "unexpected mode %v", fi.Mode())
+
+	// existing directories are passed through
+	fi, err = cfs.Lstat(tempdir)
+	test.OK(t, err)
+	test.Assert(t, fi.Sys() != nil, "FileInfo of %v is not from the local file system", tempdir)
+
+	f, err := cfs.OpenFile(hello, fs.O_RDONLY|fs.O_NOFOLLOW, 0)
+	test.OK(t, err)
+	buf, err := io.ReadAll(f)
+	test.OK(t, err)
+	test.OK(t, f.Close())
+	test.Equals(t, "hello world", strings.TrimSpace(string(buf)))
+
+	_, err = cfs.Lstat(filepath.Join(tempdir, "missing"))
+	test.Assert(t, errors.Is(err, os.ErrNotExist), "unexpected error %v", err)
+}
+
+func TestCommandFilesFail(t *testing.T) {
+	cfs := fs.NewCommandFiles(context.TODO(), fs.Local{}, time.Now(), io.Discard)
+	target := filepath.Join(test.TempDir(t), "fail")
+	test.OK(t, cfs.Add(target, []string{"false"}))
+
+	f, err := cfs.Open(target)
+	test.OK(t, err)
+	_, err = io.ReadAll(f)
+	test.Assert(t, err != nil && !errors.IsFatal(err), "expected non-fatal error, got %v", err)
+	test.Assert(t, strings.Contains(err.Error(), "false"), "error %q does not contain the command", err)
+	_ = f.Close()
+}
@@ -0,0 +1,216 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// MountSnapshot is a point-in-time view of a mounted file system.
+type MountSnapshot interface {
+	// Path returns the directory which contains the content of the mount
+	// point at the time the snapshot was created.
+	Path() string
+	// Delete removes the snapshot.
+	Delete(ctx context.Context) error
+}
+
+// partialSnapshot is implemented by snapshots which do not contain some
+// directories below the mount point, like nested btrfs subvolumes.
+type partialSnapshot interface {
+	// Missing returns the absolute paths of these directories, which are read
+	// from the live file system.
+	Missing() []string
+}
+
+// SnapshotProvider creates snapshots of mounted file systems.
+type SnapshotProvider interface {
+	CreateSnapshot(ctx context.Context, mount Mount) (MountSnapshot, error)
+}
+
+// LocalSnapshot is a wrapper around the local file system which reads all
+// files below the mount points of the backup targets from file system
+// snapshots, in a transparent way like LocalVss.
+type LocalSnapshot struct {
+	FS
+	provider   SnapshotProvider
+	mounts     []Mount
+	snapshots  map[string]MountSnapshot
+	deleted    bool
+	mutex      sync.RWMutex
+	msgError   ErrorHandler
+	msgMessage MessageHandler
+}
+
+// statically ensure that LocalSnapshot implements FS.
+var _ FS = &LocalSnapshot{}
+
+// NewLocalSnapshot creates a new wrapper around the local file system. The
+// snapshots are created by provider when calling CreateSnapshots.
+func NewLocalSnapshot(provider SnapshotProvider, msgError ErrorHandler, msgMessage MessageHandler) (*LocalSnapshot, error) {
+	mounts, err := ReadMounts()
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalSnapshot{
+		FS:         Local{},
+		provider:   provider,
+		mounts:     mounts,
+		snapshots:  make(map[string]MountSnapshot),
+		msgError:   msgError,
+		msgMessage: msgMessage,
+	}, nil
+}
+
+// CreateSnapshots creates a snapshot of each file system one of the targets
+// is located on. If a snapshot cannot be created, the error is reported and
+// the files are read from the live file system instead.
+func (fs *LocalSnapshot) CreateSnapshots(ctx context.Context, targets []string) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	failed := make(map[string]struct{})
+	for _, target := range targets {
+		abstarget, err := filepath.Abs(target)
+		if err != nil {
+			return err
+		}
+
+		mount, ok := findMount(fs.mounts, abstarget)
+		if !ok {
+			continue
+		}
+		if _, ok := fs.snapshots[mount.MountPoint]; ok {
+			continue
+		}
+		if _, ok := failed[mount.MountPoint]; ok {
+			continue
+		}
+
+		if fs.deleted {
+			return errors.New("snapshots have already been deleted")
+		}
+
+		fs.msgMessage("creating snapshot for [%s]\n", mount.MountPoint)
+		snapshot, err := fs.provider.CreateSnapshot(ctx, mount)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			failed[mount.MountPoint] = struct{}{}
+			err = fs.msgError(mount.MountPoint, errors.Errorf("failed to create snapshot for [%s]: %s", mount.MountPoint, err))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		fs.snapshots[mount.MountPoint] = snapshot
+		fs.msgMessage("successfully created snapshot for [%s]\n", mount.MountPoint)
+		if partial, ok := snapshot.(partialSnapshot); ok {
+			for _, missing := range partial.Missing() {
+				fs.msgMessage("[%s] is not contained in the snapshot and is read from the live file system\n", missing)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DeleteSnapshots deletes all snapshots that were created. It is safe to call
+// DeleteSnapshots several times, e.g. from a signal handler.
+func (fs *LocalSnapshot) DeleteSnapshots() {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	fs.deleted = true
+	activeSnapshots := make(map[string]MountSnapshot)
+
+	for mountPoint, snapshot := range fs.snapshots {
+		// the context of the backup may already be cancelled
+		if err := snapshot.Delete(context.Background()); err != nil {
+			_ = fs.msgError(mountPoint, errors.Errorf("failed to delete snapshot: %s", err))
+			activeSnapshots[mountPoint] = snapshot
+		}
+	}
+
+	fs.snapshots = activeSnapshots
+}
+
+// Open wraps the Open method of the underlying file system.
+func (fs *LocalSnapshot) Open(name string) (File, error) {
+	return fs.FS.Open(fs.snapshotPath(name))
+}
+
+// OpenFile wraps the OpenFile method of the underlying file system.
+func (fs *LocalSnapshot) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return fs.FS.OpenFile(fs.snapshotPath(name), flag, perm)
+}
+
+// Stat wraps the Stat method of the underlying file system.
+func (fs *LocalSnapshot) Stat(name string) (os.FileInfo, error) {
+	return fs.FS.Stat(fs.snapshotPath(name))
+}
+
+// Lstat wraps the Lstat method of the underlying file system.
+func (fs *LocalSnapshot) Lstat(name string) (os.FileInfo, error) {
+	return fs.FS.Lstat(fs.snapshotPath(name))
+}
+
+// snapshotPath returns the path of name within the snapshot of the file
+// system it is located on. If there is no such snapshot, name is returned.
+func (fs *LocalSnapshot) snapshotPath(name string) string {
+	absname, err := filepath.Abs(name)
+	if err != nil {
+		return name
+	}
+
+	mount, ok := findMount(fs.mounts, absname)
+	if !ok {
+		return name
+	}
+
+	fs.mutex.RLock()
+	snapshot, ok := fs.snapshots[mount.MountPoint]
+	fs.mutex.RUnlock()
+	if !ok {
+		return name
+	}
+
+	if partial, ok := snapshot.(partialSnapshot); ok {
+		for _, missing := range partial.Missing() {
+			if HasPathPrefix(missing, absname) {
+				return name
+			}
+		}
+	}
+
+	rel, err := filepath.Rel(mount.MountPoint, absname)
+	if err != nil {
+		return name
+	}
+
+	return filepath.Join(snapshot.Path(), rel)
+}
+
+// findMount returns the mount the absolute path p is located on. If a mount
+// point is listed several times, the last entry wins as it hides the others.
+func findMount(mounts []Mount, p string) (Mount, bool) {
+	var res Mount
+	found := false
+	for _, mount := range mounts {
+		if !HasPathPrefix(mount.MountPoint, p) {
+			continue
+		}
+		if !found || len(mount.MountPoint) >= len(res.MountPoint) {
+			res = mount
+			found = true
+		}
+	}
+	return res, found
+}
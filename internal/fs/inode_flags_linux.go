@@ -0,0 +1,108 @@
+package fs
+
+import (
+	"golang.org/x/sys/unix"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// Inode flags from linux/fs.h, see ioctl_iflags(2).
+const (
+	fsSecRmFl       = 0x00000001
+	fsUnRmFl        = 0x00000002
+	fsComprFl       = 0x00000004
+	fsSyncFl        = 0x00000008
+	fsImmutableFl   = 0x00000010
+	fsAppendFl      = 0x00000020
+	fsNoDumpFl      = 0x00000040
+	fsNoAtimeFl     = 0x00000080
+	fsJournalDataFl = 0x00004000
+	fsNoTailFl      = 0x00008000
+	fsDirSyncFl     = 0x00010000
+	fsTopDirFl      = 0x00020000
+	fsNoCowFl       = 0x00800000
+	fsProjInheritFl = 0x20000000
+)
+
+// InodeFlagNoDump marks files which should not be included in backups.
+const InodeFlagNoDump = fsNoDumpFl
+
+// InodeFlagsMask contains the inode flags which can be changed with chattr
+// and are thus saved and restored. Other flags, like FS_EXTENT_FL, are
+// managed by the file system.
+const InodeFlagsMask = fsSecRmFl | fsUnRmFl | fsComprFl | fsSyncFl |
+	fsImmutableFl | fsAppendFl | fsNoDumpFl | fsNoAtimeFl | fsJournalDataFl |
+	fsNoTailFl | fsDirSyncFl | fsTopDirFl | fsNoCowFl | fsProjInheritFl
+
+// openForInodeFlags opens the file or directory at path for reading or
+// changing the inode flags. Symlinks are followed, as the directories on the
+// path to a backup target are saved with the metadata of the symlink target.
+func openForInodeFlags(path string) (int, error) {
+	return unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+}
+
+// inodeFlagsUnsupported returns true if err signals that the file system
+// does not support inode flags.
+func inodeFlagsUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EINVAL)
+}
+
+// GetInodeFlags returns the inode flags (as shown by lsattr) of the regular
+// file or directory at path, restricted to InodeFlagsMask. If the file system
+// does not support inode flags, zero is returned.
+func GetInodeFlags(path string) (uint32, error) {
+	fd, err := openForInodeFlags(path)
+	if err != nil {
+		return 0, pathError("open", path, err)
+	}
+	defer func() {
+		_ = unix.Close(fd)
+	}()
+
+	return getInodeFlags(fd, path)
+}
+
+// GetFileInodeFlags returns the inode flags of the open regular file or
+// directory f, like GetInodeFlags.
+func GetFileInodeFlags(f File) (uint32, error) {
+	return getInodeFlags(int(f.Fd()), f.Name())
+}
+
+func getInodeFlags(fd int, path string) (uint32, error) {
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if inodeFlagsUnsupported(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, pathError("FS_IOC_GETFLAGS", path, err)
+	}
+	return flags & InodeFlagsMask, nil
+}
+
+// SetInodeFlags sets the inode flags in InodeFlagsMask of the regular file or
+// directory at path to flags. All other flags are kept.
+func SetInodeFlags(path string, flags uint32) error {
+	fd, err := openForInodeFlags(path)
+	if err != nil {
+		return pathError("open", path, err)
+	}
+	defer func() {
+		_ = unix.Close(fd)
+	}()
+
+	current, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if err != nil {
+		return pathError("FS_IOC_GETFLAGS", path, err)
+	}
+
+	newFlags := current&^InodeFlagsMask | flags&InodeFlagsMask
+	if newFlags == current {
+		return nil
+	}
+
+	err = unix.IoctlSetPointerInt(fd, unix.FS_IOC_SETFLAGS, int(newFlags))
+	if err != nil {
+		return pathError("FS_IOC_SETFLAGS", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestInodeFlags(t *testing.T) {
+	filename := filepath.Join(rtest.TempDir(t), "file")
+	rtest.OK(t, os.WriteFile(filename, []byte("foo"), 0600))
+
+	flags, err := GetInodeFlags(filename)
+	rtest.OK(t, err)
+	rtest.Equals(t, uint32(0), flags&InodeFlagNoDump)
+
+	rtest.OK(t, SetInodeFlags(filename, flags|InodeFlagNoDump))
+	flags, err = GetInodeFlags(filename)
+	rtest.OK(t, err)
+	if flags&InodeFlagNoDump == 0 {
+		t.Skip("file system does not support inode flags")
+	}
+
+	rtest.OK(t, SetInodeFlags(filename, flags&^InodeFlagNoDump))
+	flags, err = GetInodeFlags(filename)
+	rtest.OK(t, err)
+	rtest.Equals(t, uint32(0), flags&InodeFlagNoDump)
+}
@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package fs
+
+import "github.com/restic/restic/internal/errors"
+
+// Inode flags are only supported on Linux.
+const (
+	InodeFlagNoDump = 0
+	InodeFlagsMask  = 0
+)
+
+// GetInodeFlags is not supported on this platform.
+func GetInodeFlags(path string) (uint32, error) {
+	return 0, errors.New("inode flags are only supported on Linux")
+}
+
+// GetFileInodeFlags is not supported on this platform.
+func GetFileInodeFlags(f File) (uint32, error) {
+	return 0, errors.New("inode flags are only supported on Linux")
+}
+
+// SetInodeFlags is not supported on this platform.
+func SetInodeFlags(path string, flags uint32) error {
+	return errors.New("inode flags are only supported on Linux")
+}
@@ -0,0 +1,14 @@
+package fs
+
+// Mount describes a mounted file system.
+type Mount struct {
+	// MountPoint is the directory the file system is mounted at.
+	MountPoint string
+	// Root is the directory within the file system which is mounted at
+	// MountPoint, usually "/".
+	Root string
+	// FSType is the type of the file system, e.g. btrfs or ext4.
+	FSType string
+	// Source is the device the file system is stored on.
+	Source string
+}
@@ -0,0 +1,82 @@
+package fs
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// ReadMounts returns the file systems mounted in the mount namespace of the
+// current process.
+func ReadMounts() ([]Mount, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	return parseMountInfo(f)
+}
+
+// parseMountInfo parses the format of /proc/self/mountinfo, which is
+// described in proc(5).
+func parseMountInfo(rd io.Reader) ([]Mount, error) {
+	var mounts []Mount
+
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+
+		// the optional fields are terminated by a single hyphen
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || len(fields) < sep+3 {
+			return nil, errors.Errorf("invalid mountinfo line %q", sc.Text())
+		}
+
+		mounts = append(mounts, Mount{
+			Root:       unescapeMountInfo(fields[3]),
+			MountPoint: unescapeMountInfo(fields[4]),
+			FSType:     fields[sep+1],
+			Source:     unescapeMountInfo(fields[sep+2]),
+		})
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return mounts, nil
+}
+
+// unescapeMountInfo replaces the octal escape sequences the kernel uses for
+// spaces and other special characters in mountinfo.
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				buf.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String()
+}
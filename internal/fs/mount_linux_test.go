@@ -0,0 +1,26 @@
+package fs
+
+import (
+	"strings"
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestParseMountInfo(t *testing.T) {
+	mountinfo := `22 1 0:21 / / rw,relatime shared:1 - btrfs /dev/sda2 rw,space_cache
+36 22 253:1 /@home /home rw,noatime shared:2 master:1 - btrfs /dev/sda2 rw
+40 36 253:3 / /home/with\040space rw - ext4 /dev/mapper/vg-data rw
+`
+
+	mounts, err := parseMountInfo(strings.NewReader(mountinfo))
+	rtest.OK(t, err)
+	rtest.Equals(t, []Mount{
+		{MountPoint: "/", Root: "/", FSType: "btrfs", Source: "/dev/sda2"},
+		{MountPoint: "/home", Root: "/@home", FSType: "btrfs", Source: "/dev/sda2"},
+		{MountPoint: "/home/with space", Root: "/", FSType: "ext4", Source: "/dev/mapper/vg-data"},
+	}, mounts)
+
+	_, err = parseMountInfo(strings.NewReader("22 1 0:21 / / rw\n"))
+	rtest.Assert(t, err != nil, "missing error for invalid line")
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package fs
+
+import "github.com/restic/restic/internal/errors"
+
+// ReadMounts is only supported on Linux.
+func ReadMounts() ([]Mount, error) {
+	return nil, errors.New("listing mounted file systems is only supported on Linux")
+}
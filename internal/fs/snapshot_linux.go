@@ -0,0 +1,317 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// SnapshotProviderOptions configures the snapshot provider returned by
+// NewSnapshotProvider.
+type SnapshotProviderOptions struct {
+	// Provider is one of auto, btrfs, lvm or command.
+	Provider string
+
+	// CreateCommand and DestroyCommand are the shell commands used by the
+	// command provider.
+	CreateCommand  string
+	DestroyCommand string
+}
+
+// NewSnapshotProvider returns the provider configured in opts. The auto
+// provider uses btrfs snapshots for btrfs file systems and LVM snapshots for
+// file systems on device mapper devices.
+func NewSnapshotProvider(opts SnapshotProviderOptions) (SnapshotProvider, error) {
+	if opts.Provider != "command" && (opts.CreateCommand != "" || opts.DestroyCommand != "") {
+		return nil, errors.Errorf("snapshot commands require the command provider")
+	}
+
+	switch opts.Provider {
+	case "auto", "":
+		return autoSnapshotProvider{}, nil
+	case "btrfs":
+		return btrfsSnapshotProvider{}, nil
+	case "lvm":
+		return lvmSnapshotProvider{}, nil
+	case "command":
+		if opts.CreateCommand == "" || opts.DestroyCommand == "" {
+			return nil, errors.New("the command provider requires both a create and a destroy command")
+		}
+		return commandSnapshotProvider{create: opts.CreateCommand, destroy: opts.DestroyCommand}, nil
+	default:
+		return nil, errors.Errorf("unknown snapshot provider %q", opts.Provider)
+	}
+}
+
+// runSnapshotCommand runs the command and returns its output. The output on
+// stderr is included in the returned error.
+func runSnapshotCommand(ctx context.Context, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return "", errors.Errorf("%v failed: %v: %v", name, err, msg)
+		}
+		return "", errors.Errorf("%v failed: %v", name, err)
+	}
+
+	return stdout.String(), nil
+}
+
+// randomSnapshotName returns a unique name for a new snapshot.
+func randomSnapshotName(prefix string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf), nil
+}
+
+// autoSnapshotProvider selects the provider based on the file system.
+type autoSnapshotProvider struct{}
+
+func (autoSnapshotProvider) CreateSnapshot(ctx context.Context, mount Mount) (MountSnapshot, error) {
+	switch {
+	case mount.FSType == "btrfs":
+		return btrfsSnapshotProvider{}.CreateSnapshot(ctx, mount)
+	case strings.HasPrefix(mount.Source, "/dev/mapper/") || strings.HasPrefix(mount.Source, "/dev/dm-"):
+		return lvmSnapshotProvider{}.CreateSnapshot(ctx, mount)
+	default:
+		return nil, errors.Errorf("snapshots of %v file systems on %v are not supported", mount.FSType, mount.Source)
+	}
+}
+
+// btrfsSnapshotProvider creates read-only snapshots of btrfs subvolumes. The
+// snapshot is stored in a hidden directory below the mount point. Snapshots
+// are not recursive, so nested subvolumes are read from the live file system.
+type btrfsSnapshotProvider struct{}
+
+// btrfsSnapshotPrefix is the prefix of the names of the snapshots.
+const btrfsSnapshotPrefix = ".restic-snapshot-"
+
+type btrfsSnapshot struct {
+	path   string
+	nested []string
+}
+
+func (btrfsSnapshotProvider) CreateSnapshot(ctx context.Context, mount Mount) (MountSnapshot, error) {
+	if mount.FSType != "btrfs" {
+		return nil, errors.Errorf("%v is not a btrfs file system", mount.MountPoint)
+	}
+
+	out, err := runSnapshotCommand(ctx, nil, "btrfs", "subvolume", "list", "-o", mount.MountPoint)
+	if err != nil {
+		return nil, err
+	}
+	nested := parseBtrfsSubvolumes(out, mount)
+
+	name, err := randomSnapshotName(btrfsSnapshotPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(mount.MountPoint, name)
+	_, err = runSnapshotCommand(ctx, nil, "btrfs", "subvolume", "snapshot", "-r", mount.MountPoint, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &btrfsSnapshot{path: path, nested: nested}, nil
+}
+
+// parseBtrfsSubvolumes returns the paths of the subvolumes below mount from
+// the output of `btrfs subvolume list -o`. The listed paths are relative to
+// the top level subvolume of the file system. Snapshots created by restic are
+// ignored.
+func parseBtrfsSubvolumes(out string, mount Mount) []string {
+	root := strings.Trim(mount.Root, "/")
+
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		idx := strings.Index(line, " path ")
+		if idx < 0 {
+			continue
+		}
+		p := line[idx+len(" path "):]
+		if root != "" {
+			if !HasPathPrefix(root, p) {
+				continue
+			}
+			p = strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		}
+		if p == "" || strings.HasPrefix(filepath.Base(p), btrfsSnapshotPrefix) {
+			continue
+		}
+		paths = append(paths, filepath.Join(mount.MountPoint, p))
+	}
+	return paths
+}
+
+func (s *btrfsSnapshot) Path() string {
+	return s.path
+}
+
+func (s *btrfsSnapshot) Missing() []string {
+	return s.nested
+}
+
+func (s *btrfsSnapshot) Delete(ctx context.Context) error {
+	_, err := runSnapshotCommand(ctx, nil, "btrfs", "subvolume", "delete", s.path)
+	return err
+}
+
+// lvmSnapshotProvider creates a snapshot of the logical volume containing the
+// file system and mounts it read-only in a temporary directory.
+type lvmSnapshotProvider struct{}
+
+// lvmSnapshotSize is the size of the snapshot volume, relative to the size
+// of the origin volume.
+const lvmSnapshotSize = "10%ORIGIN"
+
+type lvmSnapshot struct {
+	volume   string
+	mountDir string
+	path     string
+}
+
+func (lvmSnapshotProvider) CreateSnapshot(ctx context.Context, mount Mount) (MountSnapshot, error) {
+	out, err := runSnapshotCommand(ctx, nil, "lvs", "--noheadings", "--separator", "/", "-o", "vg_name,lv_name", mount.Source)
+	if err != nil {
+		return nil, err
+	}
+	origin := strings.TrimSpace(out)
+	vg, lv, ok := strings.Cut(origin, "/")
+	if !ok {
+		return nil, errors.Errorf("%v is not a logical volume", mount.Source)
+	}
+
+	name, err := randomSnapshotName(lv + "-restic-")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = runSnapshotCommand(ctx, nil, "lvcreate", "--snapshot", "--extents", lvmSnapshotSize, "--name", name, origin)
+	if err != nil {
+		return nil, err
+	}
+	s := &lvmSnapshot{volume: vg + "/" + name}
+
+	s.mountDir, err = os.MkdirTemp("", "restic-lvm-snapshot-")
+	if err != nil {
+		return nil, s.cleanup(err)
+	}
+
+	_, err = runSnapshotCommand(ctx, nil, "mount", "-t", mount.FSType, "-o", lvmMountOptions(mount.FSType), "/dev/"+s.volume, s.mountDir)
+	if err != nil {
+		return nil, s.cleanup(err)
+	}
+
+	s.path = filepath.Join(s.mountDir, mount.Root)
+	return s, nil
+}
+
+// lvmMountOptions returns the options for mounting a snapshot of a file system
+// of type fstype. The snapshot of a mounted file system contains an unclean
+// journal, which must not be replayed when mounting it read-only.
+func lvmMountOptions(fstype string) string {
+	switch fstype {
+	case "ext3", "ext4":
+		return "ro,noload"
+	case "xfs":
+		// the snapshot also has the same UUID as the origin
+		return "ro,nouuid,norecovery"
+	default:
+		return "ro"
+	}
+}
+
+// cleanup removes the parts of the snapshot which have already been created
+// and returns err.
+func (s *lvmSnapshot) cleanup(err error) error {
+	if s.mountDir != "" {
+		_ = os.Remove(s.mountDir)
+	}
+	if _, rerr := runSnapshotCommand(context.Background(), nil, "lvremove", "--force", s.volume); rerr != nil {
+		return errors.Errorf("%v, removing snapshot %v failed: %v", err, s.volume, rerr)
+	}
+	return err
+}
+
+func (s *lvmSnapshot) Path() string {
+	return s.path
+}
+
+func (s *lvmSnapshot) Delete(ctx context.Context) error {
+	if _, err := runSnapshotCommand(ctx, nil, "umount", s.mountDir); err != nil {
+		return err
+	}
+	if err := os.Remove(s.mountDir); err != nil {
+		return err
+	}
+	_, err := runSnapshotCommand(ctx, nil, "lvremove", "--force", s.volume)
+	return err
+}
+
+// commandSnapshotProvider runs user-provided shell commands to create and
+// destroy snapshots. The mount is passed to the commands in the environment
+// variables RESTIC_FS_SNAPSHOT_MOUNT_POINT, RESTIC_FS_SNAPSHOT_SOURCE and
+// RESTIC_FS_SNAPSHOT_FSTYPE. The create command must print the directory
+// containing the snapshot of the mount point, which is passed to the destroy
+// command in RESTIC_FS_SNAPSHOT_PATH.
+type commandSnapshotProvider struct {
+	create, destroy string
+}
+
+type commandSnapshot struct {
+	destroy string
+	env     []string
+	path    string
+}
+
+func (p commandSnapshotProvider) CreateSnapshot(ctx context.Context, mount Mount) (MountSnapshot, error) {
+	env := []string{
+		"RESTIC_FS_SNAPSHOT_MOUNT_POINT=" + mount.MountPoint,
+		"RESTIC_FS_SNAPSHOT_SOURCE=" + mount.Source,
+		"RESTIC_FS_SNAPSHOT_FSTYPE=" + mount.FSType,
+	}
+
+	out, err := runSnapshotCommand(ctx, env, "sh", "-c", p.create)
+	if err != nil {
+		return nil, err
+	}
+
+	// use the last line of the output
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	path := strings.TrimSpace(lines[len(lines)-1])
+	if !filepath.IsAbs(path) {
+		return nil, errors.Errorf("create command returned %q instead of the absolute path of the snapshot", path)
+	}
+
+	return &commandSnapshot{
+		destroy: p.destroy,
+		env:     append(env, "RESTIC_FS_SNAPSHOT_PATH="+path),
+		path:    path,
+	}, nil
+}
+
+func (s *commandSnapshot) Path() string {
+	return s.path
+}
+
+func (s *commandSnapshot) Delete(ctx context.Context) error {
+	_, err := runSnapshotCommand(ctx, s.env, "sh", "-c", s.destroy)
+	return err
+}
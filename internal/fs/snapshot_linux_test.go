@@ -0,0 +1,162 @@
+package fs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestFindMount(t *testing.T) {
+	mounts := []Mount{
+		{MountPoint: "/", Root: "/", FSType: "btrfs", Source: "/dev/sda2"},
+		{MountPoint: "/home", Root: "/@home", FSType: "btrfs", Source: "/dev/sda2"},
+		{MountPoint: "/home/with space", Root: "/", FSType: "ext4", Source: "/dev/mapper/vg-data"},
+	}
+
+	for _, test := range []struct {
+		path, mountPoint string
+	}{
+		{"/", "/"},
+		{"/etc/passwd", "/"},
+		{"/home", "/home"},
+		{"/home/user", "/home"},
+		{"/home/with space/file", "/home/with space"},
+		{"/home/with spaces", "/home"},
+	} {
+		mount, ok := findMount(mounts, test.path)
+		rtest.Assert(t, ok, "no mount found for %v", test.path)
+		rtest.Equals(t, test.mountPoint, mount.MountPoint)
+	}
+}
+
+func TestParseBtrfsSubvolumes(t *testing.T) {
+	out := `ID 257 gen 12 top level 256 path @/var/lib/docker
+ID 258 gen 13 top level 256 path @/srv/with space
+ID 259 gen 14 top level 256 path @/.restic-snapshot-0123456789abcdef
+ID 260 gen 15 top level 5 path @home/user
+`
+
+	for _, test := range []struct {
+		mount Mount
+		paths []string
+	}{
+		{
+			Mount{MountPoint: "/", Root: "/@", FSType: "btrfs"},
+			[]string{"/var/lib/docker", "/srv/with space"},
+		},
+		{
+			Mount{MountPoint: "/home", Root: "/@home", FSType: "btrfs"},
+			[]string{"/home/user"},
+		},
+		{
+			Mount{MountPoint: "/mnt", Root: "/", FSType: "btrfs"},
+			[]string{"/mnt/@/var/lib/docker", "/mnt/@/srv/with space", "/mnt/@home/user"},
+		},
+	} {
+		rtest.Equals(t, test.paths, parseBtrfsSubvolumes(out, test.mount))
+	}
+}
+
+func TestLvmMountOptions(t *testing.T) {
+	for fstype, opts := range map[string]string{
+		"ext4":  "ro,noload",
+		"ext3":  "ro,noload",
+		"xfs":   "ro,nouuid,norecovery",
+		"btrfs": "ro",
+	} {
+		rtest.Equals(t, opts, lvmMountOptions(fstype))
+	}
+}
+
+type testPartialSnapshot struct {
+	path    string
+	missing []string
+}
+
+func (s testPartialSnapshot) Path() string                   { return s.path }
+func (s testPartialSnapshot) Missing() []string              { return s.missing }
+func (s testPartialSnapshot) Delete(_ context.Context) error { return nil }
+
+func TestLocalSnapshotPartial(t *testing.T) {
+	fs := &LocalSnapshot{
+		FS:     Local{},
+		mounts: []Mount{{MountPoint: "/", Root: "/@", FSType: "btrfs"}},
+		snapshots: map[string]MountSnapshot{
+			"/": testPartialSnapshot{path: "/.restic-snapshot-1", missing: []string{"/var/lib/docker"}},
+		},
+	}
+
+	rtest.Equals(t, "/.restic-snapshot-1/var/lib", fs.snapshotPath("/var/lib"))
+	// nested subvolumes are read from the live file system
+	rtest.Equals(t, "/var/lib/docker", fs.snapshotPath("/var/lib/docker"))
+	rtest.Equals(t, "/var/lib/docker/file", fs.snapshotPath("/var/lib/docker/file"))
+}
+
+func TestLocalSnapshotCommand(t *testing.T) {
+	tempdir := rtest.TempDir(t)
+	src := filepath.Join(tempdir, "src")
+	snapshotDir := filepath.Join(tempdir, "snapshot")
+	rtest.OK(t, os.MkdirAll(filepath.Join(src, "dir"), 0700))
+	rtest.OK(t, os.WriteFile(filepath.Join(src, "dir", "file"), []byte("old"), 0600))
+
+	provider, err := NewSnapshotProvider(SnapshotProviderOptions{
+		Provider:       "command",
+		CreateCommand:  `cp -a "$RESTIC_FS_SNAPSHOT_MOUNT_POINT" "` + snapshotDir + `" && echo "` + snapshotDir + `"`,
+		DestroyCommand: `rm -rf "$RESTIC_FS_SNAPSHOT_PATH"`,
+	})
+	rtest.OK(t, err)
+
+	var messages []string
+	fs := &LocalSnapshot{
+		FS:        Local{},
+		provider:  provider,
+		mounts:    []Mount{{MountPoint: src, Root: "/", FSType: "ext4", Source: "/dev/test"}},
+		snapshots: make(map[string]MountSnapshot),
+		msgError: func(item string, err error) error {
+			t.Fatalf("unexpected error for %v: %v", item, err)
+			return nil
+		},
+		msgMessage: func(msg string, args ...interface{}) {
+			messages = append(messages, msg)
+		},
+	}
+
+	rtest.OK(t, fs.CreateSnapshots(context.TODO(), []string{filepath.Join(src, "dir")}))
+	rtest.Equals(t, 2, len(messages))
+
+	// changes after creating the snapshot must not be visible
+	rtest.OK(t, os.WriteFile(filepath.Join(src, "dir", "file"), []byte("new"), 0600))
+
+	f, err := fs.Open(filepath.Join(src, "dir", "file"))
+	rtest.OK(t, err)
+	buf := make([]byte, 3)
+	_, err = f.Read(buf)
+	rtest.OK(t, err)
+	rtest.OK(t, f.Close())
+	rtest.Equals(t, "old", string(buf))
+
+	// paths outside of the mount point are read from the live file system
+	rtest.Equals(t, tempdir, fs.snapshotPath(tempdir))
+
+	fs.DeleteSnapshots()
+	_, err = os.Stat(snapshotDir)
+	rtest.Assert(t, os.IsNotExist(err), "snapshot was not removed: %v", err)
+
+	// deleting the snapshots again is a no-op
+	fs.DeleteSnapshots()
+}
+
+func TestNewSnapshotProvider(t *testing.T) {
+	for _, opts := range []SnapshotProviderOptions{
+		{Provider: "invalid"},
+		{Provider: "command"},
+		{Provider: "command", CreateCommand: "true"},
+		{Provider: "btrfs", CreateCommand: "true", DestroyCommand: "true"},
+	} {
+		_, err := NewSnapshotProvider(opts)
+		rtest.Assert(t, err != nil, "missing error for %+v", opts)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package fs
+
+import "github.com/restic/restic/internal/errors"
+
+// SnapshotProviderOptions configures the snapshot provider returned by
+// NewSnapshotProvider.
+type SnapshotProviderOptions struct {
+	Provider       string
+	CreateCommand  string
+	DestroyCommand string
+}
+
+// NewSnapshotProvider is only supported on Linux.
+func NewSnapshotProvider(_ SnapshotProviderOptions) (SnapshotProvider, error) {
+	return nil, errors.New("file system snapshot providers are only supported on Linux")
+}
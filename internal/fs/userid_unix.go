@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package fs
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// UserID extracts the ID of the owner from an os.FileInfo object by casting
+// it to syscall.Stat_t
+func UserID(fi os.FileInfo) (uid uint32, err error) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return st.Uid, nil
+	}
+
+	return 0, errors.New("Could not cast to syscall.Stat_t")
+}
@@ -0,0 +1,16 @@
+//go:build windows
+// +build windows
+
+package fs
+
+import (
+	"os"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// UserID extracts the ID of the owner from an os.FileInfo object by casting
+// it to syscall.Stat_t
+func UserID(fi os.FileInfo) (uid uint32, err error) {
+	return 0, errors.New("User IDs are not supported on Windows")
+}
@@ -80,16 +80,31 @@ func newDirFromSnapshot(root *Root, inode uint64, snapshot *restic.Snapshot) (*d
 	return &dir{
 		root: root,
 		node: &restic.Node{
-			AccessTime: snapshot.Time,
-			ModTime:    snapshot.Time,
-			ChangeTime: snapshot.Time,
-			Mode:       os.ModeDir | 0555,
-			Subtree:    snapshot.Tree,
+			AccessTime:         snapshot.Time,
+			ModTime:            snapshot.Time,
+			ChangeTime:         snapshot.Time,
+			Mode:               os.ModeDir | 0555,
+			Subtree:            snapshot.Tree,
+			ExtendedAttributes: snapshotXattrs(snapshot),
 		},
 		inode: inode,
 	}, nil
 }
 
+// snapshotDescriptionXattr is the extended attribute of a snapshot directory
+// which contains the description of the snapshot.
+const snapshotDescriptionXattr = "user.restic.description"
+
+// snapshotXattrs returns the extended attributes for the directory of snapshot.
+func snapshotXattrs(snapshot *restic.Snapshot) []restic.ExtendedAttribute {
+	if snapshot.Description == "" {
+		return nil
+	}
+	return []restic.ExtendedAttribute{
+		{Name: snapshotDescriptionXattr, Value: []byte(snapshot.Description)},
+	}
+}
+
 func (d *dir) open(ctx context.Context) error {
 	d.m.Lock()
 	defer d.m.Unlock()
@@ -177,6 +177,29 @@ func TestFuseDir(t *testing.T) {
 	rtest.Equals(t, node.ModTime, attr.Mtime)
 }
 
+func TestSnapshotDirDescription(t *testing.T) {
+	sn := &restic.Snapshot{Time: time.Unix(1606773731, 0), Description: "pre-upgrade"}
+	d, err := newDirFromSnapshot(&Root{}, 42, sn)
+	rtest.OK(t, err)
+
+	exp := &fuse.ListxattrResponse{}
+	exp.Append(snapshotDescriptionXattr)
+	resp := &fuse.ListxattrResponse{}
+	rtest.OK(t, d.Listxattr(context.TODO(), &fuse.ListxattrRequest{}, resp))
+	rtest.Equals(t, exp.Xattr, resp.Xattr)
+
+	getResp := &fuse.GetxattrResponse{}
+	rtest.OK(t, d.Getxattr(context.TODO(), &fuse.GetxattrRequest{Name: snapshotDescriptionXattr}, getResp))
+	rtest.Equals(t, []byte("pre-upgrade"), getResp.Xattr)
+
+	// snapshots without description have no extended attributes
+	d, err = newDirFromSnapshot(&Root{}, 42, &restic.Snapshot{Time: sn.Time})
+	rtest.OK(t, err)
+	resp = &fuse.ListxattrResponse{}
+	rtest.OK(t, d.Listxattr(context.TODO(), &fuse.ListxattrRequest{}, resp))
+	rtest.Equals(t, 0, len(resp.Xattr))
+}
+
 // Test top-level directories for their UID and GID.
 func TestTopUIDGID(t *testing.T) {
 	repo := repository.TestRepository(t)
@@ -64,7 +64,9 @@ func pathsFromSn(pathTemplate string, timeTemplate string, sn *restic.Snapshot)
 	inVerb := false
 	writeTime := false
 	out := make([]strings.Builder, 1)
-	for _, c := range pathTemplate {
+	template := []rune(pathTemplate)
+	for pos := 0; pos < len(template); pos++ {
+		c := template[pos]
 		if writeTime {
 			for i := range out {
 				out[i].WriteString(timeformat)
@@ -121,6 +123,20 @@ func pathsFromSn(pathTemplate string, timeTemplate string, sn *restic.Snapshot)
 		case 'h':
 			repl = sn.Hostname
 
+		case 'l':
+			key, length, ok := labelKey(template[pos+1:])
+			if !ok {
+				repl = string(c)
+				break
+			}
+			pos += length
+
+			value, ok := sn.Labels[key]
+			if !ok {
+				return nil, ""
+			}
+			repl = filenameFromTag(value)
+
 		default:
 			repl = string(c)
 		}
@@ -142,6 +158,23 @@ func pathsFromSn(pathTemplate string, timeTemplate string, sn *restic.Snapshot)
 	return paths, timeSuffix
 }
 
+// labelKey parses the key of a label variable in the format "{key}" at the
+// start of s. It returns the key and the number of runes consumed.
+func labelKey(s []rune) (key string, length int, ok bool) {
+	if len(s) == 0 || s[0] != '{' {
+		return "", 0, false
+	}
+	for i, c := range s {
+		if c == '}' {
+			if i == 1 {
+				return "", 0, false
+			}
+			return string(s[1:i]), i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
 // Some tags are problematic when used as filenames:
 //
 //	""
@@ -174,7 +207,7 @@ outer:
 		}
 		inVerb = false
 		switch c {
-		case 'i', 'I', 'u', 'h', 't', 'T':
+		case 'i', 'I', 'u', 'h', 't', 'T', 'l':
 			patternStart = i
 			break outer
 		}
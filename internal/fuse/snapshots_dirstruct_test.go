@@ -15,7 +15,7 @@ import (
 func TestPathsFromSn(t *testing.T) {
 	id1, _ := restic.ParseID("1234567812345678123456781234567812345678123456781234567812345678")
 	time1, _ := time.Parse("2006-01-02T15:04:05", "2021-01-01T00:00:01")
-	sn1 := &restic.Snapshot{Hostname: "host", Username: "user", Tags: []string{"tag1", "tag2"}, Time: time1}
+	sn1 := &restic.Snapshot{Hostname: "host", Username: "user", Tags: []string{"tag1", "tag2"}, Labels: map[string]string{"app": "db/main"}, Time: time1}
 	restic.TestSetSnapshotID(t, sn1, id1)
 
 	var p []string
@@ -52,6 +52,18 @@ func TestPathsFromSn(t *testing.T) {
 	p, s = pathsFromSn("%T/%i", "2006/01", sn1)
 	test.Equals(t, []string{"2021/01/12345678"}, p)
 	test.Equals(t, "", s)
+
+	p, s = pathsFromSn("labels/%l{app}/%T", "2006-01-02T15:04:05", sn1)
+	test.Equals(t, []string{"labels/db_main/"}, p)
+	test.Equals(t, "2021-01-01T00:00:01", s)
+
+	p, s = pathsFromSn("labels/%l{env}/%T", "2006-01-02T15:04:05", sn1)
+	test.Equals(t, []string(nil), p)
+	test.Equals(t, "", s)
+
+	p, s = pathsFromSn("labels/%l/%i", "2006-01-02T15:04:05", sn1)
+	test.Equals(t, []string{"labels/l/12345678"}, p)
+	test.Equals(t, "", s)
 }
 
 func TestMakeDirs(t *testing.T) {
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"sync"
 
 	"github.com/restic/restic/internal/restic"
 	"golang.org/x/sync/errgroup"
@@ -15,15 +16,22 @@ type SavePacker interface {
 type uploadTask struct {
 	packer *Packer
 	tpe    restic.BlobType
+	done   chan struct{}
 }
 
 type packerUploader struct {
 	uploadQueue chan uploadTask
+
+	// pending contains the done channels of all packers which have been
+	// queued but not uploaded yet
+	pendingMu sync.Mutex
+	pending   map[chan struct{}]struct{}
 }
 
 func newPackerUploader(ctx context.Context, wg *errgroup.Group, repo SavePacker, connections uint) *packerUploader {
 	pu := &packerUploader{
 		uploadQueue: make(chan uploadTask),
+		pending:     make(map[chan struct{}]struct{}),
 	}
 
 	for i := 0; i < int(connections); i++ {
@@ -38,6 +46,7 @@ func newPackerUploader(ctx context.Context, wg *errgroup.Group, repo SavePacker,
 					if err != nil {
 						return err
 					}
+					pu.finish(t.done)
 				case <-ctx.Done():
 					return ctx.Err()
 				}
@@ -49,10 +58,44 @@ func newPackerUploader(ctx context.Context, wg *errgroup.Group, repo SavePacker,
 }
 
 func (pu *packerUploader) QueuePacker(ctx context.Context, t restic.BlobType, p *Packer) (err error) {
+	done := make(chan struct{})
+	pu.pendingMu.Lock()
+	pu.pending[done] = struct{}{}
+	pu.pendingMu.Unlock()
+
 	select {
 	case <-ctx.Done():
+		pu.finish(done)
 		return ctx.Err()
-	case pu.uploadQueue <- uploadTask{tpe: t, packer: p}:
+	case pu.uploadQueue <- uploadTask{tpe: t, packer: p, done: done}:
+	}
+
+	return nil
+}
+
+func (pu *packerUploader) finish(done chan struct{}) {
+	pu.pendingMu.Lock()
+	delete(pu.pending, done)
+	pu.pendingMu.Unlock()
+	close(done)
+}
+
+// Wait blocks until all packers which were queued before the call have been
+// uploaded. Packers queued concurrently are not waited for.
+func (pu *packerUploader) Wait(ctx context.Context) error {
+	pu.pendingMu.Lock()
+	pending := make([]chan struct{}, 0, len(pu.pending))
+	for done := range pu.pending {
+		pending = append(pending, done)
+	}
+	pu.pendingMu.Unlock()
+
+	for _, done := range pending {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	return nil
@@ -595,6 +595,31 @@ func (r *Repository) StartPackUploader(ctx context.Context, wg *errgroup.Group)
 	})
 }
 
+// FlushPending uploads all pending packs and saves the index. In contrast to
+// Flush, the pack uploader keeps running and SaveBlob may be called
+// concurrently. All blobs saved before the call are contained in the index
+// afterwards.
+func (r *Repository) FlushPending(ctx context.Context) error {
+	if r.packerWg == nil {
+		return nil
+	}
+
+	if err := r.treePM.Flush(ctx); err != nil {
+		return err
+	}
+	if err := r.dataPM.Flush(ctx); err != nil {
+		return err
+	}
+	if err := r.uploader.Wait(ctx); err != nil {
+		return err
+	}
+
+	if r.noAutoIndexUpdate {
+		return nil
+	}
+	return r.idx.SaveIndex(ctx, r)
+}
+
 // FlushPacks saves all remaining packs.
 func (r *Repository) flushPacks(ctx context.Context) error {
 	if r.packerWg == nil {
@@ -79,6 +79,33 @@ func testSave(t *testing.T, version uint, calculateID bool) {
 	}
 }
 
+func TestFlushPending(t *testing.T) {
+	repo := repository.TestRepository(t)
+
+	var wg errgroup.Group
+	repo.StartPackUploader(context.TODO(), &wg)
+
+	data := rtest.Random(23, 1<<16)
+	id, _, _, err := repo.SaveBlob(context.TODO(), restic.DataBlob, data, restic.ID{}, false)
+	rtest.OK(t, err)
+
+	rtest.OK(t, repo.FlushPending(context.TODO()))
+	rtest.Assert(t, repo.Index().Has(restic.BlobHandle{ID: id, Type: restic.DataBlob}),
+		"blob %v is missing from the index after FlushPending", id.Str())
+
+	buf, err := repo.LoadBlob(context.TODO(), restic.DataBlob, id, nil)
+	rtest.OK(t, err)
+	rtest.Assert(t, bytes.Equal(buf, data), "data does not match")
+
+	// the pack uploader must still be usable
+	data2 := rtest.Random(42, 1<<16)
+	id2, _, _, err := repo.SaveBlob(context.TODO(), restic.DataBlob, data2, restic.ID{}, false)
+	rtest.OK(t, err)
+	rtest.OK(t, repo.Flush(context.TODO()))
+	rtest.Assert(t, repo.Index().Has(restic.BlobHandle{ID: id2, Type: restic.DataBlob}),
+		"blob %v is missing from the index after Flush", id2.Str())
+}
+
 func BenchmarkSaveAndEncrypt(t *testing.B) {
 	repository.BenchmarkAllVersions(t, benchmarkSaveAndEncrypt)
 }
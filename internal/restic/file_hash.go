@@ -0,0 +1,64 @@
+package restic
+
+import (
+	"encoding/hex"
+	"hash"
+	"strings"
+
+	"github.com/minio/sha256-simd"
+	"github.com/restic/restic/internal/errors"
+)
+
+// FileHashSHA256 is the name of the SHA-256 algorithm for whole-file hashes.
+const FileHashSHA256 = "sha256"
+
+// NewFileHash returns a new hash for the algorithm algo. The resulting
+// checksum is stored in Node.FileHash using FormatFileHash.
+func NewFileHash(algo string) (hash.Hash, error) {
+	switch algo {
+	case FileHashSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, errors.Errorf("unsupported file hash algorithm %q", algo)
+	}
+}
+
+// FormatFileHash returns the string representation of the checksum sum
+// computed with algorithm algo, in the format algo:hex.
+func FormatFileHash(algo string, sum []byte) string {
+	return algo + ":" + hex.EncodeToString(sum)
+}
+
+// ParseFileHash splits a file hash in the format algo:hex into the algorithm
+// and the checksum. A hash without an algorithm is treated as SHA-256.
+func ParseFileHash(s string) (algo string, sum []byte, err error) {
+	algo, hexSum, found := strings.Cut(s, ":")
+	if !found {
+		algo, hexSum = FileHashSHA256, s
+	}
+
+	h, err := NewFileHash(algo)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sum, err = hex.DecodeString(hexSum)
+	if err != nil {
+		return "", nil, errors.Errorf("invalid file hash %q: %v", s, err)
+	}
+	if len(sum) != h.Size() {
+		return "", nil, errors.Errorf("invalid length for file hash %q", s)
+	}
+
+	return algo, sum, nil
+}
+
+// FileHashAlgorithm returns the algorithm used for the file hash of node, or
+// the empty string if node has no file hash.
+func (node *Node) FileHashAlgorithm() string {
+	if node.FileHash == "" {
+		return ""
+	}
+	algo, _, _ := strings.Cut(node.FileHash, ":")
+	return algo
+}
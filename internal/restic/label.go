@@ -0,0 +1,50 @@
+package restic
+
+import (
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// checkLabelKey returns an error if key cannot be used as the key of a label.
+// Keys must not be empty and must not contain '=' or ',', the latter is used to
+// separate the options for --group-by.
+func checkLabelKey(key string) error {
+	if key == "" {
+		return errors.New("label key is empty")
+	}
+	if strings.ContainsAny(key, "=,") {
+		return errors.Errorf("label key %q contains invalid character, '=' and ',' are not allowed", key)
+	}
+	return nil
+}
+
+// ParseLabel splits a label in the format key=value into key and value.
+func ParseLabel(s string) (key, value string, err error) {
+	key, value, found := strings.Cut(s, "=")
+	if !found {
+		return "", "", errors.Errorf("invalid label %q, expected format key=value", s)
+	}
+	if err := checkLabelKey(key); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// ParseLabels parses a list of labels in the format key=value. If a key is
+// given several times, the last value is used.
+func ParseLabels(list []string) (map[string]string, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	labels := make(map[string]string, len(list))
+	for _, s := range list {
+		key, value, err := ParseLabel(s)
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
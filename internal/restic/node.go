@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/user"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -49,12 +50,19 @@ const (
 	// TypeFileAttributes is the GenericAttributeType used for storing file attributes for windows files within the generic attributes map.
 	TypeFileAttributes GenericAttributeType = "windows.file_attributes"
 
+	// Below are linux specific attributes.
+
+	// TypeLinuxFlags is the GenericAttributeType used for storing the inode flags for linux files within the generic attributes map.
+	TypeLinuxFlags GenericAttributeType = "linux.flags"
+	// TypeLinuxBirthTime is the GenericAttributeType used for storing the birth time reported by statx for linux files within the generic attributes map.
+	TypeLinuxBirthTime GenericAttributeType = "linux.birth_time"
+
 	// Generic Attributes for other OS types should be defined here.
 )
 
 // init is called when the package is initialized. Any new GenericAttributeTypes being created must be added here as well.
 func init() {
-	storeGenericAttributeType(TypeCreationTime, TypeFileAttributes)
+	storeGenericAttributeType(TypeCreationTime, TypeFileAttributes, TypeLinuxFlags, TypeLinuxBirthTime)
 }
 
 // genericAttributesForOS maintains a map of known genericAttributesForOS to the OSType
@@ -97,6 +105,14 @@ type Node struct {
 	Content            IDs                                      `json:"content"`
 	Subtree            *ID                                      `json:"subtree,omitempty"`
 
+	// ChangedDuringBackup is set for files which were modified while being
+	// read, the content may thus be inconsistent.
+	ChangedDuringBackup bool `json:"changed_during_backup,omitempty"`
+
+	// FileHash is an optional checksum of the whole file content in the
+	// format algorithm:hex, e.g. sha256:e3b0c442...
+	FileHash string `json:"file_hash,omitempty"`
+
 	Error string `json:"error,omitempty"`
 
 	Path string `json:"-"`
@@ -132,9 +148,15 @@ func (node Node) String() string {
 		mode|node.Mode, node.UID, node.GID, node.Size, node.ModTime, node.Name)
 }
 
-// NodeFromFileInfo returns a new node from the given path and FileInfo. It
+// XattrSelectFunc returns true if the extended attribute name should be saved
+// or restored. A nil XattrSelectFunc selects all extended attributes.
+type XattrSelectFunc func(name string) bool
+
+// NodeFromFileInfo returns a new node from the given path and FileInfo. Only
+// the extended attributes selected by selectXattr are saved. On Linux, the
+// inode flags and the birth time are only saved if withInodeFlags is set. It
 // returns the first error that is encountered, together with a node.
-func NodeFromFileInfo(path string, fi os.FileInfo) (*Node, error) {
+func NodeFromFileInfo(path string, fi os.FileInfo, selectXattr XattrSelectFunc, withInodeFlags bool) (*Node, error) {
 	mask := os.ModePerm | os.ModeType | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
 	node := &Node{
 		Path:    path,
@@ -148,7 +170,7 @@ func NodeFromFileInfo(path string, fi os.FileInfo) (*Node, error) {
 		node.Size = uint64(fi.Size())
 	}
 
-	err := node.fillExtra(path, fi)
+	err := node.fillExtra(path, fi, selectXattr, withInodeFlags)
 	return node, err
 }
 
@@ -224,8 +246,8 @@ func (node *Node) CreateAt(ctx context.Context, path string, repo BlobLoader) er
 }
 
 // RestoreMetadata restores node metadata
-func (node Node) RestoreMetadata(path string, warn func(msg string)) error {
-	err := node.restoreMetadata(path, warn)
+func (node Node) RestoreMetadata(path string, warn func(msg string), selectXattr XattrSelectFunc) error {
+	err := node.restoreMetadata(path, warn, selectXattr)
 	if err != nil {
 		debug.Log("restoreMetadata(%s) error %v", path, err)
 	}
@@ -233,7 +255,7 @@ func (node Node) RestoreMetadata(path string, warn func(msg string)) error {
 	return err
 }
 
-func (node Node) restoreMetadata(path string, warn func(msg string)) error {
+func (node Node) restoreMetadata(path string, warn func(msg string), selectXattr XattrSelectFunc) error {
 	var firsterr error
 
 	if err := lchown(path, int(node.UID), int(node.GID)); err != nil {
@@ -254,7 +276,7 @@ func (node Node) restoreMetadata(path string, warn func(msg string)) error {
 		}
 	}
 
-	if err := node.restoreExtendedAttributes(path); err != nil {
+	if err := node.restoreExtendedAttributes(path, selectXattr); err != nil {
 		debug.Log("error restoring extended attributes for %v: %v", path, err)
 		if firsterr != nil {
 			firsterr = err
@@ -282,8 +304,12 @@ func (node Node) restoreMetadata(path string, warn func(msg string)) error {
 	return firsterr
 }
 
-func (node Node) restoreExtendedAttributes(path string) error {
+func (node Node) restoreExtendedAttributes(path string, selectXattr XattrSelectFunc) error {
 	for _, attr := range node.ExtendedAttributes {
+		if selectXattr != nil && !selectXattr(attr.Name) {
+			debug.Log("not restoring extended attribute %v for %v", attr.Name, path)
+			continue
+		}
 		err := Setxattr(path, attr.Name, attr.Value)
 		if err != nil {
 			return err
@@ -486,6 +512,12 @@ func (node Node) Equals(other Node) bool {
 	if node.Device != other.Device {
 		return false
 	}
+	if node.ChangedDuringBackup != other.ChangedDuringBackup {
+		return false
+	}
+	if node.FileHash != other.FileHash {
+		return false
+	}
 	if !node.sameContent(other) {
 		return false
 	}
@@ -675,7 +707,12 @@ func lookupGroup(gid uint32) string {
 	return group
 }
 
-func (node *Node) fillExtra(path string, fi os.FileInfo) error {
+func (node *Node) fillExtra(path string, fi os.FileInfo, selectXattr XattrSelectFunc, withInodeFlags bool) error {
+	if info, ok := fi.Sys().(*fs.ArchiveEntryInfo); ok {
+		node.fillArchiveEntryInfo(info, selectXattr)
+		return nil
+	}
+
 	stat, ok := toStatT(fi.Sys())
 	if !ok {
 		// fill minimal info with current values for uid, gid
@@ -716,10 +753,10 @@ func (node *Node) fillExtra(path string, fi os.FileInfo) error {
 		return errors.Errorf("unsupported file type %q", node.Type)
 	}
 
-	allowExtended, err := node.fillGenericAttributes(path, fi, stat)
+	allowExtended, err := node.fillGenericAttributes(path, fi, stat, withInodeFlags)
 	if allowExtended {
 		// Skip processing ExtendedAttributes if allowExtended is false.
-		errEx := node.fillExtendedAttributes(path)
+		errEx := node.fillExtendedAttributes(path, selectXattr)
 		if err == nil {
 			err = errEx
 		} else {
@@ -729,7 +766,50 @@ func (node *Node) fillExtra(path string, fi os.FileInfo) error {
 	return err
 }
 
-func (node *Node) fillExtendedAttributes(path string) error {
+// fillArchiveEntryInfo fills the node with the metadata of an entry of an
+// fs.Archive, as it cannot be read from the local file system. Only the
+// extended attributes selected by selectXattr are saved.
+func (node *Node) fillArchiveEntryInfo(info *fs.ArchiveEntryInfo, selectXattr XattrSelectFunc) {
+	node.UID, node.GID = info.UID, info.GID
+	node.User, node.Group = info.User, info.Group
+	node.Inode = info.Inode
+	node.AccessTime = info.AccessTime
+	node.ChangeTime = info.ChangeTime
+	if node.AccessTime.IsZero() {
+		node.AccessTime = node.ModTime
+	}
+	if node.ChangeTime.IsZero() {
+		node.ChangeTime = node.ModTime
+	}
+
+	switch node.Type {
+	case "file":
+		node.Links = info.Links
+	case "symlink":
+		node.LinkTarget = info.LinkTarget
+		node.Links = info.Links
+	case "dev", "chardev":
+		node.Device = info.Device
+		node.Links = info.Links
+	}
+
+	names := make([]string, 0, len(info.ExtendedAttributes))
+	for name := range info.ExtendedAttributes {
+		if selectXattr != nil && !selectXattr(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		node.ExtendedAttributes = append(node.ExtendedAttributes, ExtendedAttribute{
+			Name:  name,
+			Value: []byte(info.ExtendedAttributes[name]),
+		})
+	}
+}
+
+func (node *Node) fillExtendedAttributes(path string, selectXattr XattrSelectFunc) error {
 	xattrs, err := Listxattr(path)
 	debug.Log("fillExtendedAttributes(%v) %v %v", path, xattrs, err)
 	if err != nil {
@@ -738,6 +818,11 @@ func (node *Node) fillExtendedAttributes(path string) error {
 
 	node.ExtendedAttributes = make([]ExtendedAttribute, 0, len(xattrs))
 	for _, attr := range xattrs {
+		if selectXattr != nil && !selectXattr(attr) {
+			debug.Log("not saving extended attribute %v for %v", attr, path)
+			continue
+		}
+
 		attrVal, err := Getxattr(path, attr)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "can not obtain extended attribute %v for %v:\n", attr, path)
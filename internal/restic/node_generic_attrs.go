@@ -0,0 +1,16 @@
+//go:build darwin || freebsd || solaris
+// +build darwin freebsd solaris
+
+package restic
+
+import "os"
+
+// restoreGenericAttributes is no-op.
+func (node *Node) restoreGenericAttributes(_ string, warn func(msg string)) error {
+	return node.handleAllUnknownGenericAttributesFound(warn)
+}
+
+// fillGenericAttributes is a no-op.
+func (node *Node) fillGenericAttributes(_ string, _ os.FileInfo, _ *statT, _ bool) (allowExtended bool, err error) {
+	return true, nil
+}
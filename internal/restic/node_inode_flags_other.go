@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package restic
+
+// RestoreInodeFlags is a no-op, inode flags are only supported on Linux.
+func (node Node) RestoreInodeFlags(_ string) error {
+	return nil
+}
@@ -1,15 +1,32 @@
 package restic
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
 	"syscall"
+	"time"
 
 	"golang.org/x/sys/unix"
 
+	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/fs"
 )
 
+// LinuxAttributes are the genericAttributes for Linux
+type LinuxAttributes struct {
+	// Flags is used for storing the inode flags as shown by lsattr, see
+	// fs.InodeFlagsMask. It is only set for files and directories with at
+	// least one flag.
+	Flags *uint32 `generic:"flags"`
+	// BirthTime is used for storing the creation time reported by statx.
+	BirthTime *time.Time `generic:"birth_time"`
+}
+
 func (node Node) restoreSymlinkTimestamps(path string, utimes [2]syscall.Timespec) error {
 	dir, err := fs.Open(filepath.Dir(path))
 	if err != nil {
@@ -35,3 +52,107 @@ func (node Node) restoreSymlinkTimestamps(path string, utimes [2]syscall.Timespe
 func (s statT) atim() syscall.Timespec { return s.Atim }
 func (s statT) mtim() syscall.Timespec { return s.Mtim }
 func (s statT) ctim() syscall.Timespec { return s.Ctim }
+
+// restoreGenericAttributes checks the generic attributes for Linux. The birth
+// time cannot be set on Linux, the inode flags are restored separately by
+// RestoreInodeFlags.
+func (node *Node) restoreGenericAttributes(path string, warn func(msg string)) error {
+	if len(node.GenericAttributes) == 0 {
+		return nil
+	}
+	_, unknownAttribs, err := genericAttributesToLinuxAttrs(node.GenericAttributes)
+	if err != nil {
+		return fmt.Errorf("error parsing generic attribute for: %s : %v", path, err)
+	}
+	HandleUnknownGenericAttributesFound(unknownAttribs, warn)
+	return nil
+}
+
+// RestoreInodeFlags sets the inode flags stored for node on path. Flags like
+// immutable or append-only prevent any further modification, so this must be
+// called after the content and all other metadata have been restored.
+func (node Node) RestoreInodeFlags(path string) error {
+	if node.Type != "file" && node.Type != "dir" {
+		return nil
+	}
+
+	linuxAttributes, _, err := genericAttributesToLinuxAttrs(node.GenericAttributes)
+	if err != nil {
+		return fmt.Errorf("error parsing generic attribute for: %s : %v", path, err)
+	}
+	if linuxAttributes.Flags == nil {
+		return nil
+	}
+
+	err = fs.SetInodeFlags(path, *linuxAttributes.Flags)
+	// Setting flags like immutable requires CAP_LINUX_IMMUTABLE, so like for
+	// lchown only report permission errors if we run as root.
+	if err != nil && os.Geteuid() > 0 && errors.Is(err, unix.EPERM) {
+		debug.Log("not running as root, ignoring permission error setting inode flags for %v: %v", path, err)
+		return nil
+	}
+	return err
+}
+
+// genericAttributesToLinuxAttrs converts the generic attributes map to a LinuxAttributes and also returns a string of unknown attributes that it could not convert.
+func genericAttributesToLinuxAttrs(attrs map[GenericAttributeType]json.RawMessage) (linuxAttributes LinuxAttributes, unknownAttribs []GenericAttributeType, err error) {
+	laValue := reflect.ValueOf(&linuxAttributes).Elem()
+	unknownAttribs, err = genericAttributesToOSAttrs(attrs, reflect.TypeOf(linuxAttributes), &laValue, "linux")
+	return linuxAttributes, unknownAttribs, err
+}
+
+// fillGenericAttributes fills in the generic attributes for Linux, the inode
+// flags and the birth time. They are only collected if withInodeFlags is set.
+func (node *Node) fillGenericAttributes(path string, _ os.FileInfo, _ *statT, withInodeFlags bool) (allowExtended bool, err error) {
+	if !withInodeFlags {
+		return true, nil
+	}
+
+	var linuxAttributes LinuxAttributes
+
+	linuxAttributes.BirthTime = getBirthTime(path)
+
+	// inode flags can only be read from files and directories
+	if node.Type == "file" || node.Type == "dir" {
+		flags, err := fs.GetInodeFlags(path)
+		if err != nil {
+			// e.g. a file which is readable by nobody, like for file systems
+			// without inode flags the node is saved without flags
+			debug.Log("unable to read inode flags of %v: %v", path, err)
+			flags = 0
+		}
+		if flags != 0 {
+			linuxAttributes.Flags = &flags
+		}
+	}
+
+	node.GenericAttributes, err = LinuxAttrsToGenericAttributes(linuxAttributes)
+	return true, err
+}
+
+// LinuxAttrsToGenericAttributes converts the LinuxAttributes to a generic attributes map using reflection
+func LinuxAttrsToGenericAttributes(linuxAttributes LinuxAttributes) (attrs map[GenericAttributeType]json.RawMessage, err error) {
+	linuxAttributesValue := reflect.ValueOf(linuxAttributes)
+	attrs, err = osAttrsToGenericAttributes(reflect.TypeOf(linuxAttributes), &linuxAttributesValue, runtime.GOOS)
+	if len(attrs) == 0 {
+		return nil, err
+	}
+	return attrs, err
+}
+
+// getBirthTime returns the birth time of path as reported by statx, or nil if
+// the file system does not provide it.
+func getBirthTime(path string) *time.Time {
+	var stx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, unix.AT_SYMLINK_NOFOLLOW|unix.AT_STATX_DONT_SYNC, unix.STATX_BTIME, &stx)
+	if err != nil {
+		debug.Log("statx for %v failed: %v", path, err)
+		return nil
+	}
+	if stx.Mask&unix.STATX_BTIME == 0 {
+		return nil
+	}
+
+	btime := time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec)).UTC()
+	return &btime
+}
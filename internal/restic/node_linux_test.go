@@ -0,0 +1,113 @@
+package restic
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/restic/restic/internal/fs"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestNodeInodeFlags(t *testing.T) {
+	tempdir := rtest.TempDir(t)
+	source := filepath.Join(tempdir, "source")
+	rtest.OK(t, os.WriteFile(source, []byte("foo"), 0600))
+	rtest.OK(t, fs.SetInodeFlags(source, fs.InodeFlagNoDump))
+	if flags, err := fs.GetInodeFlags(source); err != nil || flags&fs.InodeFlagNoDump == 0 {
+		t.Skipf("file system does not support inode flags: %v", err)
+	}
+
+	fi, err := os.Lstat(source)
+	rtest.OK(t, err)
+	node, err := NodeFromFileInfo(source, fi, nil, false)
+	rtest.OK(t, err)
+	rtest.Assert(t, node.GenericAttributes == nil, "inode flags stored without withInodeFlags")
+
+	node, err = NodeFromFileInfo(source, fi, nil, true)
+	rtest.OK(t, err)
+
+	attrs, unknown, err := genericAttributesToLinuxAttrs(node.GenericAttributes)
+	rtest.OK(t, err)
+	rtest.Equals(t, 0, len(unknown))
+	rtest.Assert(t, attrs.Flags != nil, "inode flags not stored")
+	rtest.Equals(t, uint32(fs.InodeFlagNoDump), *attrs.Flags)
+
+	target := filepath.Join(tempdir, "target")
+	rtest.OK(t, os.WriteFile(target, []byte("foo"), 0600))
+	rtest.OK(t, node.RestoreInodeFlags(target))
+
+	flags, err := fs.GetInodeFlags(target)
+	rtest.OK(t, err)
+	rtest.Equals(t, uint32(fs.InodeFlagNoDump), flags)
+}
+
+func TestNodeBirthTime(t *testing.T) {
+	filename := filepath.Join(rtest.TempDir(t), "file")
+	rtest.OK(t, os.WriteFile(filename, []byte("foo"), 0600))
+
+	btime := getBirthTime(filename)
+	if btime == nil {
+		t.Skip("file system does not report the birth time")
+	}
+
+	fi, err := os.Lstat(filename)
+	rtest.OK(t, err)
+	node, err := NodeFromFileInfo(filename, fi, nil, true)
+	rtest.OK(t, err)
+
+	attrs, _, err := genericAttributesToLinuxAttrs(node.GenericAttributes)
+	rtest.OK(t, err)
+	rtest.Assert(t, attrs.BirthTime != nil, "birth time not stored")
+	rtest.Assert(t, attrs.BirthTime.Equal(*btime), "wrong birth time, want %v, got %v", *btime, *attrs.BirthTime)
+	rtest.Assert(t, attrs.Flags == nil, "unexpected inode flags %v", attrs.Flags)
+}
+
+func TestNodeInodeFlagsUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can open all files")
+	}
+
+	filename := filepath.Join(rtest.TempDir(t), "file")
+	rtest.OK(t, os.WriteFile(filename, []byte("foo"), 0000))
+
+	fi, err := os.Lstat(filename)
+	rtest.OK(t, err)
+	node, err := NodeFromFileInfo(filename, fi, nil, true)
+	rtest.OK(t, err)
+
+	attrs, _, err := genericAttributesToLinuxAttrs(node.GenericAttributes)
+	rtest.OK(t, err)
+	rtest.Assert(t, attrs.Flags == nil, "unexpected inode flags %v", attrs.Flags)
+}
+
+func TestNodeSelectXattr(t *testing.T) {
+	tempdir := rtest.TempDir(t)
+	source := filepath.Join(tempdir, "source")
+	rtest.OK(t, os.WriteFile(source, []byte("foo"), 0600))
+	for _, name := range []string{"user.foo", "user.bar"} {
+		if err := Setxattr(source, name, []byte("value")); err != nil {
+			t.Skipf("file system does not support extended attributes: %v", err)
+		}
+	}
+
+	selectXattr := func(name string) bool {
+		return name != "user.bar"
+	}
+
+	fi, err := os.Lstat(source)
+	rtest.OK(t, err)
+	node, err := NodeFromFileInfo(source, fi, selectXattr, false)
+	rtest.OK(t, err)
+	rtest.Equals(t, []ExtendedAttribute{{Name: "user.foo", Value: []byte("value")}}, node.ExtendedAttributes)
+
+	// restore all attributes, but only apply the selected ones
+	node.ExtendedAttributes = append(node.ExtendedAttributes, ExtendedAttribute{Name: "user.bar", Value: []byte("value")})
+	target := filepath.Join(tempdir, "target")
+	rtest.OK(t, os.WriteFile(target, []byte("foo"), 0600))
+	rtest.OK(t, node.RestoreMetadata(target, func(msg string) { t.Fatal(msg) }, selectXattr))
+
+	names, err := Listxattr(target)
+	rtest.OK(t, err)
+	rtest.Equals(t, []string{"user.foo"}, names)
+}
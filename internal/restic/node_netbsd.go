@@ -34,6 +34,6 @@ func (node *Node) restoreGenericAttributes(_ string, warn func(msg string)) erro
 }
 
 // fillGenericAttributes is a no-op on netbsd.
-func (node *Node) fillGenericAttributes(_ string, _ os.FileInfo, _ *statT) (allowExtended bool, err error) {
+func (node *Node) fillGenericAttributes(_ string, _ os.FileInfo, _ *statT, _ bool) (allowExtended bool, err error) {
 	return true, nil
 }
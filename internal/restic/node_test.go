@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/restic/restic/internal/fs"
 	"github.com/restic/restic/internal/test"
 	rtest "github.com/restic/restic/internal/test"
 )
@@ -31,7 +32,7 @@ func BenchmarkNodeFillUser(t *testing.B) {
 	t.ResetTimer()
 
 	for i := 0; i < t.N; i++ {
-		_, err := NodeFromFileInfo(path, fi)
+		_, err := NodeFromFileInfo(path, fi, nil, false)
 		rtest.OK(t, err)
 	}
 
@@ -55,7 +56,7 @@ func BenchmarkNodeFromFileInfo(t *testing.B) {
 	t.ResetTimer()
 
 	for i := 0; i < t.N; i++ {
-		_, err := NodeFromFileInfo(path, fi)
+		_, err := NodeFromFileInfo(path, fi, nil, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -218,7 +219,7 @@ func TestNodeRestoreAt(t *testing.T) {
 				nodePath = filepath.Join(tempdir, test.Name)
 			}
 			rtest.OK(t, test.CreateAt(context.TODO(), nodePath, nil))
-			rtest.OK(t, test.RestoreMetadata(nodePath, func(msg string) { rtest.OK(t, fmt.Errorf("Warning triggered for path: %s: %s", nodePath, msg)) }))
+			rtest.OK(t, test.RestoreMetadata(nodePath, func(msg string) { rtest.OK(t, fmt.Errorf("Warning triggered for path: %s: %s", nodePath, msg)) }, nil))
 
 			if test.Type == "dir" {
 				rtest.OK(t, test.RestoreTimestamps(nodePath))
@@ -227,7 +228,7 @@ func TestNodeRestoreAt(t *testing.T) {
 			fi, err := os.Lstat(nodePath)
 			rtest.OK(t, err)
 
-			n2, err := NodeFromFileInfo(nodePath, fi)
+			n2, err := NodeFromFileInfo(nodePath, fi, nil, false)
 			rtest.OK(t, err)
 
 			rtest.Assert(t, test.Name == n2.Name,
@@ -371,3 +372,26 @@ func TestSymlinkSerializationFormat(t *testing.T) {
 		test.Assert(t, n2.LinkTargetRaw == nil, "quoted link target is just a helper field and must be unset after decoding")
 	}
 }
+
+type archiveFileInfo struct {
+	os.FileInfo
+	info *fs.ArchiveEntryInfo
+}
+
+func (fi archiveFileInfo) Name() string       { return "file" }
+func (fi archiveFileInfo) Mode() os.FileMode  { return 0644 }
+func (fi archiveFileInfo) ModTime() time.Time { return time.Unix(1700000000, 0) }
+func (fi archiveFileInfo) Size() int64        { return 0 }
+func (fi archiveFileInfo) Sys() interface{}   { return fi.info }
+
+func TestNodeArchiveEntrySelectXattr(t *testing.T) {
+	fi := archiveFileInfo{info: &fs.ArchiveEntryInfo{
+		ExtendedAttributes: map[string]string{"user.foo": "foo", "user.bar": "bar"},
+	}}
+
+	node, err := NodeFromFileInfo("/file", fi, func(name string) bool {
+		return name != "user.bar"
+	}, false)
+	rtest.OK(t, err)
+	rtest.Equals(t, []ExtendedAttribute{{Name: "user.foo", Value: []byte("foo")}}, node.ExtendedAttributes)
+}
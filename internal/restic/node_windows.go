@@ -260,7 +260,7 @@ func decryptFile(pathPointer *uint16) error {
 
 // fillGenericAttributes fills in the generic attributes for windows like File Attributes,
 // Created time etc.
-func (node *Node) fillGenericAttributes(path string, fi os.FileInfo, stat *statT) (allowExtended bool, err error) {
+func (node *Node) fillGenericAttributes(path string, fi os.FileInfo, stat *statT, _ bool) (allowExtended bool, err error) {
 	if strings.Contains(filepath.Base(path), ":") {
 		//Do not process for Alternate Data Streams in Windows
 		// Also do not allow processing of extended attributes for ADS.
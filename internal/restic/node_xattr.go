@@ -4,7 +4,6 @@
 package restic
 
 import (
-	"os"
 	"syscall"
 
 	"github.com/restic/restic/internal/errors"
@@ -48,13 +47,3 @@ func handleXattrErr(err error) error {
 		return errors.WithStack(e)
 	}
 }
-
-// restoreGenericAttributes is no-op.
-func (node *Node) restoreGenericAttributes(_ string, warn func(msg string)) error {
-	return node.handleAllUnknownGenericAttributesFound(warn)
-}
-
-// fillGenericAttributes is a no-op.
-func (node *Node) fillGenericAttributes(_ string, _ os.FileInfo, _ *statT) (allowExtended bool, err error) {
-	return true, nil
-}
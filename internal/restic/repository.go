@@ -52,6 +52,9 @@ type Repository interface {
 	// that error.
 	StartPackUploader(ctx context.Context, wg *errgroup.Group)
 	Flush(context.Context) error
+	// FlushPending uploads all pending packs and saves the index without
+	// stopping the pack uploader.
+	FlushPending(context.Context) error
 
 	// LoadUnpacked loads and decrypts the file with the given type and ID.
 	LoadUnpacked(ctx context.Context, t FileType, id ID) (data []byte, err error)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os/user"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,11 +26,40 @@ type Snapshot struct {
 	Tags     []string  `json:"tags,omitempty"`
 	Original *ID       `json:"original,omitempty"`
 
+	Labels      map[string]string `json:"labels,omitempty"`
+	Description string            `json:"description,omitempty"`
+
 	ProgramVersion string `json:"program_version,omitempty"`
+	// Unvisited lists the paths which were not backed up completely because
+	// the time budget for the backup was exhausted.
+	Unvisited []string         `json:"unvisited,omitempty"`
+	Summary   *SnapshotSummary `json:"summary,omitempty"`
 
 	id *ID // plaintext ID, used during restore
 }
 
+// SnapshotSummary contains statistics about the backup run that created a
+// snapshot.
+type SnapshotSummary struct {
+	BackupStart time.Time `json:"backup_start"`
+	BackupEnd   time.Time `json:"backup_end"`
+
+	// statistics from the backup json output
+	FilesNew                 uint   `json:"files_new"`
+	FilesChanged             uint   `json:"files_changed"`
+	FilesUnmodified          uint   `json:"files_unmodified"`
+	FilesChangedDuringBackup uint   `json:"files_changed_during_backup,omitempty"`
+	DirsNew                  uint   `json:"dirs_new"`
+	DirsChanged              uint   `json:"dirs_changed"`
+	DirsUnmodified           uint   `json:"dirs_unmodified"`
+	DataBlobs                int    `json:"data_blobs"`
+	TreeBlobs                int    `json:"tree_blobs"`
+	DataAdded                uint64 `json:"data_added"`
+	DataAddedPacked          uint64 `json:"data_added_packed"`
+	TotalFilesProcessed      uint   `json:"total_files_processed"`
+	TotalBytesProcessed      uint64 `json:"total_bytes_processed"`
+}
+
 // NewSnapshot returns an initialized snapshot struct for the current user and
 // time.
 func NewSnapshot(paths []string, tags []string, hostname string, time time.Time) (*Snapshot, error) {
@@ -152,6 +182,64 @@ func (sn *Snapshot) RemoveTags(removeTags []string) (changed bool) {
 	return
 }
 
+// AddLabels adds the given labels to the snapshots labels, replacing the
+// values of existing keys. It returns true if any changes were made.
+func (sn *Snapshot) AddLabels(labels map[string]string) (changed bool) {
+	for key, value := range labels {
+		if old, ok := sn.Labels[key]; ok && old == value {
+			continue
+		}
+		if sn.Labels == nil {
+			sn.Labels = make(map[string]string)
+		}
+		sn.Labels[key] = value
+		changed = true
+	}
+	return
+}
+
+// RemoveLabels removes the labels with the given keys from the snapshot and
+// returns true if any changes were made.
+func (sn *Snapshot) RemoveLabels(keys []string) (changed bool) {
+	for _, key := range keys {
+		if _, ok := sn.Labels[key]; ok {
+			delete(sn.Labels, key)
+			changed = true
+		}
+	}
+	if len(sn.Labels) == 0 {
+		sn.Labels = nil
+	}
+	return
+}
+
+// HasLabels returns true if the snapshot matches all the label filters in l.
+// A filter in the format key=value requires the label to have that value, a
+// filter consisting only of a key requires the label to be present.
+func (sn *Snapshot) HasLabels(l []string) bool {
+	for _, filter := range l {
+		key, value, hasValue := strings.Cut(filter, "=")
+		v, ok := sn.Labels[key]
+		if !ok || (hasValue && v != value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LacksLabels returns true if the snapshot has none of the labels with the
+// given keys.
+func (sn *Snapshot) LacksLabels(keys []string) bool {
+	for _, key := range keys {
+		if _, ok := sn.Labels[key]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (sn *Snapshot) hasTag(tag string) bool {
 	for _, snTag := range sn.Tags {
 		if tag == snTag {
@@ -20,16 +20,22 @@ type SnapshotFilter struct {
 	Hosts []string
 	Tags  TagLists
 	Paths []string
+	// Labels contains filters in the format key=value or key, all of them
+	// must match.
+	Labels []string
+	// MissingLabels contains the keys of labels which must not be present.
+	MissingLabels []string
 	// Match snapshots from before this timestamp. Zero for no limit.
 	TimestampLimit time.Time
 }
 
 func (f *SnapshotFilter) empty() bool {
-	return len(f.Hosts)+len(f.Tags)+len(f.Paths) == 0
+	return len(f.Hosts)+len(f.Tags)+len(f.Paths)+len(f.Labels)+len(f.MissingLabels) == 0
 }
 
 func (f *SnapshotFilter) matches(sn *Snapshot) bool {
-	return sn.HasHostname(f.Hosts) && sn.HasTagList(f.Tags) && sn.HasPaths(f.Paths)
+	return sn.HasHostname(f.Hosts) && sn.HasTagList(f.Tags) && sn.HasPaths(f.Paths) &&
+		sn.HasLabels(f.Labels) && sn.LacksLabels(f.MissingLabels)
 }
 
 // findLatest finds the latest snapshot with optional target/directory,
@@ -11,6 +11,8 @@ type SnapshotGroupByOptions struct {
 	Tag  bool
 	Host bool
 	Path bool
+	// Labels contains the keys of the labels to group by
+	Labels []string
 }
 
 func splitSnapshotGroupBy(s string) (SnapshotGroupByOptions, error) {
@@ -25,7 +27,14 @@ func splitSnapshotGroupBy(s string) (SnapshotGroupByOptions, error) {
 			l.Tag = true
 		case "":
 		default:
-			return SnapshotGroupByOptions{}, fmt.Errorf("unknown grouping option: %q", option)
+			if !strings.HasPrefix(option, "label:") {
+				return SnapshotGroupByOptions{}, fmt.Errorf("unknown grouping option: %q", option)
+			}
+			key := strings.TrimPrefix(option, "label:")
+			if err := checkLabelKey(key); err != nil {
+				return SnapshotGroupByOptions{}, fmt.Errorf("invalid grouping option %q: %w", option, err)
+			}
+			l.Labels = append(l.Labels, key)
 		}
 	}
 	return l, nil
@@ -42,6 +51,9 @@ func (l SnapshotGroupByOptions) String() string {
 	if l.Tag {
 		parts = append(parts, "tags")
 	}
+	for _, key := range l.Labels {
+		parts = append(parts, "label:"+key)
+	}
 	return strings.Join(parts, ",")
 }
 
@@ -61,9 +73,10 @@ func (l *SnapshotGroupByOptions) Type() string {
 // SnapshotGroupKey is the structure for identifying groups in a grouped
 // snapshot list. This is used by GroupSnapshots()
 type SnapshotGroupKey struct {
-	Hostname string   `json:"hostname"`
-	Paths    []string `json:"paths"`
-	Tags     []string `json:"tags"`
+	Hostname string            `json:"hostname"`
+	Paths    []string          `json:"paths"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels,omitempty"`
 }
 
 // GroupSnapshots takes a list of snapshots and a grouping criteria and creates
@@ -88,12 +101,24 @@ func GroupSnapshots(snapshots Snapshots, groupBy SnapshotGroupByOptions) (map[st
 		if groupBy.Path {
 			paths = sn.Paths
 		}
+		// a missing label is omitted to keep it apart from an empty value
+		var labels map[string]string
+		for _, key := range groupBy.Labels {
+			value, ok := sn.Labels[key]
+			if !ok {
+				continue
+			}
+			if labels == nil {
+				labels = make(map[string]string, len(groupBy.Labels))
+			}
+			labels[key] = value
+		}
 
 		sort.Strings(sn.Paths)
 		var k []byte
 		var err error
 
-		k, err = json.Marshal(SnapshotGroupKey{Tags: tags, Hostname: hostname, Paths: paths})
+		k, err = json.Marshal(SnapshotGroupKey{Tags: tags, Hostname: hostname, Paths: paths, Labels: labels})
 
 		if err != nil {
 			return nil, false, err
@@ -101,5 +126,5 @@ func GroupSnapshots(snapshots Snapshots, groupBy SnapshotGroupByOptions) (map[st
 		snapshotGroups[string(k)] = append(snapshotGroups[string(k)], sn)
 	}
 
-	return snapshotGroups, groupBy.Tag || groupBy.Host || groupBy.Path, nil
+	return snapshotGroups, groupBy.Tag || groupBy.Host || groupBy.Path || len(groupBy.Labels) > 0, nil
 }
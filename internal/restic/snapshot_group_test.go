@@ -34,6 +34,11 @@ func TestGroupByOptions(t *testing.T) {
 			opts:       restic.SnapshotGroupByOptions{Host: true, Path: true, Tag: true},
 			normalized: "host,paths,tags",
 		},
+		{
+			from:       "label:app,host,label:env",
+			opts:       restic.SnapshotGroupByOptions{Host: true, Labels: []string{"app", "env"}},
+			normalized: "host,label:app,label:env",
+		},
 	} {
 		var opts restic.SnapshotGroupByOptions
 		test.OK(t, opts.Set(exp.from))
@@ -47,4 +52,32 @@ func TestGroupByOptions(t *testing.T) {
 	err := opts.Set("tags,invalid")
 	test.Assert(t, err != nil, "missing error on invalid tags")
 	test.Assert(t, !opts.Host && !opts.Path && !opts.Tag, "unexpected opts %s %s %s", opts.Host, opts.Path, opts.Tag)
+
+	err = opts.Set("label:")
+	test.Assert(t, err != nil, "missing error on empty label key")
+}
+
+func TestGroupSnapshotsByLabel(t *testing.T) {
+	snapshots := restic.Snapshots{
+		{Labels: map[string]string{"app": "db", "env": "prod"}},
+		{Labels: map[string]string{"app": "db", "env": "test"}},
+		{Labels: map[string]string{"app": "web"}},
+		{Labels: map[string]string{"app": ""}},
+		{},
+	}
+
+	groups, grouped, err := restic.GroupSnapshots(snapshots, restic.SnapshotGroupByOptions{Labels: []string{"app"}})
+	test.OK(t, err)
+	test.Assert(t, grouped, "snapshots were not grouped")
+
+	sizes := make(map[string]int)
+	for key, list := range groups {
+		sizes[key] = len(list)
+	}
+	test.Equals(t, map[string]int{
+		`{"hostname":"","paths":null,"tags":null,"labels":{"app":"db"}}`:  2,
+		`{"hostname":"","paths":null,"tags":null,"labels":{"app":"web"}}`: 1,
+		`{"hostname":"","paths":null,"tags":null,"labels":{"app":""}}`:    1,
+		`{"hostname":"","paths":null,"tags":null}`:                        1,
+	}, sizes)
 }
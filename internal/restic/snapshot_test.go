@@ -27,6 +27,33 @@ func TestTagList(t *testing.T) {
 	rtest.Assert(t, r, "Failed to match untagged snapshot")
 }
 
+func TestLabels(t *testing.T) {
+	labels, err := restic.ParseLabels([]string{"app=db", "env=prod", "empty=", "app=web"})
+	rtest.OK(t, err)
+	rtest.Equals(t, map[string]string{"app": "web", "env": "prod", "empty": ""}, labels)
+
+	for _, invalid := range []string{"app", "=db", "a,b=c"} {
+		_, err := restic.ParseLabels([]string{invalid})
+		rtest.Assert(t, err != nil, "missing error for invalid label %q", invalid)
+	}
+
+	sn, _ := restic.NewSnapshot([]string{"/home/foobar"}, nil, "foo", time.Now())
+	rtest.Assert(t, sn.HasLabels(nil), "Failed to match snapshot without label filter")
+	rtest.Assert(t, !sn.HasLabels([]string{"app"}), "Matched snapshot without labels")
+
+	rtest.Assert(t, sn.AddLabels(labels), "AddLabels reported no change")
+	rtest.Assert(t, !sn.AddLabels(map[string]string{"app": "web"}), "AddLabels reported change for existing label")
+	rtest.Assert(t, sn.HasLabels([]string{"app=web", "env"}), "Failed to match labels")
+	rtest.Assert(t, sn.HasLabels([]string{"empty="}), "Failed to match empty label")
+	rtest.Assert(t, !sn.HasLabels([]string{"app=web", "env=test"}), "Matched label with wrong value")
+	rtest.Assert(t, sn.LacksLabels([]string{"missing"}), "Failed to match missing label")
+	rtest.Assert(t, !sn.LacksLabels([]string{"missing", "empty"}), "Matched present label as missing")
+
+	rtest.Assert(t, sn.RemoveLabels([]string{"app", "missing"}), "RemoveLabels reported no change")
+	rtest.Assert(t, !sn.RemoveLabels([]string{"app"}), "RemoveLabels reported change for missing label")
+	rtest.Equals(t, map[string]string{"env": "prod", "empty": ""}, sn.Labels)
+}
+
 func TestLoadJSONUnpacked(t *testing.T) {
 	repository.TestAllVersions(t, testLoadJSONUnpacked)
 }
@@ -0,0 +1,107 @@
+package restorer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/restic"
+)
+
+// visitExtraneous calls visit for all items in the directory target which do
+// not exist in tree and which are selected by res.SelectFilter.
+func (res *Restorer) visitExtraneous(tree *restic.Tree, target, location string,
+	visit func(target, location string) error) error {
+
+	f, err := fs.Open(target)
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing to delete in a directory which does not exist yet
+		return nil
+	}
+	if err != nil {
+		return res.Error(location, err)
+	}
+	entries, err := f.Readdirnames(-1)
+	_ = f.Close()
+	if err != nil {
+		return res.Error(location, err)
+	}
+
+	keep := make(map[string]struct{}, len(tree.Nodes))
+	for _, node := range tree.Nodes {
+		keep[comparableFilename(node.Name)] = struct{}{}
+	}
+
+	for _, entry := range entries {
+		if _, ok := keep[comparableFilename(entry)]; ok {
+			continue
+		}
+
+		entryTarget := filepath.Join(target, entry)
+		entryLocation := filepath.Join(location, entry)
+
+		fi, err := fs.Lstat(entryTarget)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			if err := res.Error(entryLocation, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// the filters only distinguish directories from other items
+		node := &restic.Node{Name: entry, Type: "file"}
+		if fi.IsDir() {
+			node.Type = "dir"
+		} else if fi.Mode()&os.ModeSymlink != 0 {
+			node.Type = "symlink"
+		}
+
+		// items which are excluded from the restore are never deleted
+		selectedForRestore, _ := res.SelectFilter(entryLocation, entryTarget, node)
+		debug.Log("SelectFilter returned %v for extraneous item %q", selectedForRestore, entryLocation)
+		if !selectedForRestore {
+			continue
+		}
+
+		if err := visit(entryTarget, entryLocation); err != nil {
+			if err := res.Error(entryLocation, err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeExtraneous removes an item which does not exist in the snapshot. For
+// a dry run, the item is only reported.
+func (res *Restorer) removeExtraneous(target, location string) error {
+	debug.Log("removing extraneous item %q, dry run: %v", target, res.DryRun)
+	if !res.DryRun {
+		if err := fs.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+
+	if res.progress != nil {
+		res.progress.AddDeletedFile(location)
+	}
+	return nil
+}
+
+// comparableFilename returns a version of name which can be compared with
+// other filenames, taking into account that the file systems on Windows and
+// macOS are usually case insensitive.
+func comparableFilename(name string) string {
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return strings.ToLower(name)
+	}
+	return name
+}
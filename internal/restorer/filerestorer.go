@@ -4,7 +4,9 @@ import (
 	"context"
 	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/restic/chunker"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/restic/restic/internal/debug"
@@ -29,8 +31,11 @@ type fileInfo struct {
 	inProgress bool
 	sparse     bool
 	size       int64
+	modTime    time.Time
 	location   string      // file on local filesystem relative to restorer basedir
 	blobs      interface{} // blobs of the file
+	existing   bool        // the file already exists, its content may be reused
+	unchanged  bool        // the existing file already had the content of the snapshot
 }
 
 type fileBlobInfo struct {
@@ -57,6 +62,10 @@ type fileRestorer struct {
 	sparse      bool
 	progress    *restore.Progress
 
+	// chunkerPol is used to split existing files into chunks to find
+	// blobs which need not be downloaded
+	chunkerPol chunker.Pol
+
 	dst   string
 	files []*fileInfo
 	Error func(string, error) error
@@ -67,6 +76,7 @@ func newFileRestorer(dst string,
 	idx func(restic.BlobHandle) []restic.PackedBlob,
 	connections uint,
 	sparse bool,
+	chunkerPol chunker.Pol,
 	progress *restore.Progress) *fileRestorer {
 
 	// as packs are streamed the concurrency is limited by IO
@@ -79,14 +89,15 @@ func newFileRestorer(dst string,
 		zeroChunk:   repository.ZeroChunk(),
 		sparse:      sparse,
 		progress:    progress,
+		chunkerPol:  chunkerPol,
 		workerCount: workerCount,
 		dst:         dst,
 		Error:       restorerAbortOnAllErrors,
 	}
 }
 
-func (r *fileRestorer) addFile(location string, content restic.IDs, size int64) {
-	r.files = append(r.files, &fileInfo{location: location, blobs: content, size: size})
+func (r *fileRestorer) addFile(location string, content restic.IDs, size int64, modTime time.Time, existing bool) {
+	r.files = append(r.files, &fileInfo{location: location, blobs: content, size: size, modTime: modTime, existing: existing})
 }
 
 func (r *fileRestorer) targetPath(location string) string {
@@ -117,20 +128,44 @@ func (r *fileRestorer) restoreFiles(ctx context.Context) error {
 	// approximation to shorten restore times by up to 19% in some test.
 	var packOrder restic.IDs
 
+	reusedBlobs, err := r.reuseExistingFiles(ctx)
+	if err != nil {
+		return err
+	}
+
 	// create packInfo from fileInfo
-	for _, file := range r.files {
+	for i, file := range r.files {
 		fileBlobs := file.blobs.(restic.IDs)
 		largeFile := len(fileBlobs) > largeFileBlobCount
+
+		reused := reusedBlobs[i]
+		// allow garbage collection
+		reusedBlobs[i] = nil
+		if reused != nil {
+			// only the missing blobs are written to the existing file,
+			// which requires their offsets
+			largeFile = true
+			file.inProgress = true
+		}
+
 		var packsMap map[restic.ID][]fileBlobInfo
 		if largeFile {
 			packsMap = make(map[restic.ID][]fileBlobInfo)
 		}
 		fileOffset := int64(0)
+		blobIndex := 0
 		err := r.forEachBlob(fileBlobs, func(packID restic.ID, blob restic.Blob) {
+			inPlace := reused != nil && reused[blobIndex]
+			blobIndex++
 			if largeFile {
-				packsMap[packID] = append(packsMap[packID], fileBlobInfo{id: blob.ID, offset: fileOffset})
+				if !inPlace {
+					packsMap[packID] = append(packsMap[packID], fileBlobInfo{id: blob.ID, offset: fileOffset})
+				}
 				fileOffset += int64(blob.DataLength())
 			}
+			if inPlace {
+				return
+			}
 			pack, ok := packs[packID]
 			if !ok {
 				pack = &packInfo{
@@ -150,6 +185,10 @@ func (r *fileRestorer) restoreFiles(ctx context.Context) error {
 			// in addition, a short chunk will never match r.zeroChunk which would prevent sparseness for short files
 			file.sparse = r.sparse
 		}
+		if reused != nil {
+			// the existing file may contain data where zeros must be written
+			file.sparse = false
+		}
 
 		if err != nil {
 			// repository index is messed up, can't do anything
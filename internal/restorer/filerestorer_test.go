@@ -4,11 +4,16 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sort"
+	"sync/atomic"
 	"testing"
 
+	"github.com/restic/chunker"
 	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
@@ -144,7 +149,7 @@ func restoreAndVerify(t *testing.T, tempdir string, content []TestFile, files ma
 	t.Helper()
 	repo := newTestRepo(content)
 
-	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, sparse, nil)
+	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, sparse, repository.TestChunkerPol, nil)
 
 	if files == nil {
 		r.files = repo.files
@@ -281,7 +286,7 @@ func TestErrorRestoreFiles(t *testing.T) {
 		return loadError
 	}
 
-	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, false, nil)
+	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, false, repository.TestChunkerPol, nil)
 	r.files = repo.files
 
 	err := r.restoreFiles(context.TODO())
@@ -322,7 +327,7 @@ func TestFatalDownloadError(t *testing.T) {
 		})
 	}
 
-	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, false, nil)
+	r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, false, repository.TestChunkerPol, nil)
 	r.files = repo.files
 
 	var errors []string
@@ -338,3 +343,87 @@ func TestFatalDownloadError(t *testing.T) {
 	rtest.Assert(t, len(errors) == 1, "unexpected number of restore errors, expected: 1, got: %v", len(errors))
 	rtest.Assert(t, errors[0] == "file2", "expected error for file2, got: %v", errors[0])
 }
+
+func TestFileRestorerReuseExisting(t *testing.T) {
+	data := rtest.Random(23, 8*1024*1024)
+
+	// split the data like the archiver does
+	var blobs []TestBlob
+	chnker := chunker.New(bytes.NewReader(data), repository.TestChunkerPol)
+	buf := make([]byte, chunker.MaxSize)
+	for {
+		chunk, err := chnker.Next(buf)
+		if err == io.EOF {
+			break
+		}
+		rtest.OK(t, err)
+		blobs = append(blobs, TestBlob{string(chunk.Data), fmt.Sprintf("pack%d", len(blobs)%3)})
+	}
+	rtest.Assert(t, len(blobs) > 3, "too few blobs: %v", len(blobs))
+	firstBlob := len(blobs[0].data)
+
+	// an interrupted restore has only written some of the blobs to the
+	// preallocated file
+	partial := make([]byte, len(data))
+	offset := 0
+	for i, blob := range blobs {
+		if i%2 == 1 {
+			copy(partial[offset:], blob.data)
+		}
+		offset += len(blob.data)
+	}
+
+	for _, test := range []struct {
+		name       string
+		existing   []byte
+		downloaded int
+		// the existing file has the modification time of the snapshot
+		sameModTime bool
+	}{
+		{"unchanged", data, 0, false},
+		{"inserted", append([]byte("inserted data"), data...), 1, false},
+		{"removed", data[100:], 1, false},
+		// blobs at their offset are kept even if the last chunk differs
+		{"appended", append(append([]byte{}, data...), "appended data"...), 0, false},
+		{"modified", append(append(append([]byte{}, data[:firstBlob]...), "modified data"...), data[firstBlob+13:]...), 1, false},
+		{"swapped", append(append([]byte{}, data[len(data)/2:]...), data[:len(data)/2]...), -1, false},
+		{"interrupted", partial, (len(blobs) + 1) / 2, false},
+		{"unrelated", rtest.Random(42, len(data)), len(blobs), false},
+		// data is only moved if size or modification time differ
+		{"swapped-same-modtime", append(append([]byte{}, data[len(data)/2:]...), data[:len(data)/2]...), len(blobs), true},
+		{"small", []byte("small file"), len(blobs), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tempdir := rtest.TempDir(t)
+			repo := newTestRepo([]TestFile{{name: "file", blobs: blobs}})
+
+			var downloaded int64
+			loader := repo.loader
+			repo.loader = func(ctx context.Context, packID restic.ID, blobs []restic.Blob, handleBlobFn func(blob restic.BlobHandle, buf []byte, err error) error) error {
+				atomic.AddInt64(&downloaded, int64(len(blobs)))
+				return loader(ctx, packID, blobs, handleBlobFn)
+			}
+
+			filename := filepath.Join(tempdir, "file")
+			rtest.OK(t, os.WriteFile(filename, test.existing, 0600))
+			fi, err := os.Stat(filename)
+			rtest.OK(t, err)
+
+			r := newFileRestorer(tempdir, repo.loader, repo.Lookup, 2, false, repository.TestChunkerPol, nil)
+			r.files = repo.files
+			for _, file := range r.files {
+				file.size = int64(len(data))
+				file.existing = true
+				if test.sameModTime {
+					file.modTime = fi.ModTime()
+				}
+			}
+			rtest.OK(t, r.restoreFiles(context.TODO()))
+
+			verifyRestore(t, r, repo)
+			if test.downloaded >= 0 {
+				rtest.Equals(t, int64(test.downloaded), downloaded)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package restorer
+
+import (
+	"os"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/restic"
+	restoreui "github.com/restic/restic/internal/ui/restore"
+)
+
+// OverwriteBehavior controls which items that already exist in the target
+// directory are overwritten during a restore.
+type OverwriteBehavior int
+
+const (
+	// OverwriteAlways restores all items, existing files are overwritten.
+	OverwriteAlways OverwriteBehavior = iota
+	// OverwriteIfChanged only restores items which differ from the snapshot.
+	// Files with the same size and modification time are not modified at
+	// all. For other existing files, only the parts of the content which
+	// differ from the snapshot are restored.
+	OverwriteIfChanged
+	// OverwriteIfNewer only overwrites items whose modification time in the
+	// snapshot is newer than that of the existing item. This includes the
+	// metadata of existing directories.
+	OverwriteIfNewer
+	// OverwriteNever never overwrites existing items, including the
+	// metadata of existing directories.
+	OverwriteNever
+)
+
+var overwriteBehaviorNames = map[OverwriteBehavior]string{
+	OverwriteAlways:    "always",
+	OverwriteIfChanged: "if-changed",
+	OverwriteIfNewer:   "if-newer",
+	OverwriteNever:     "never",
+}
+
+// Set implements the pflag.Value interface.
+func (b *OverwriteBehavior) Set(s string) error {
+	for behavior, name := range overwriteBehaviorNames {
+		if name == s {
+			*b = behavior
+			return nil
+		}
+	}
+	return errors.Errorf("invalid overwrite behavior %q, must be one of always, if-changed, if-newer or never", s)
+}
+
+// String implements the pflag.Value interface.
+func (b *OverwriteBehavior) String() string {
+	return overwriteBehaviorNames[*b]
+}
+
+// Type implements the pflag.Value interface.
+func (b *OverwriteBehavior) Type() string {
+	return "behavior"
+}
+
+// itemAction decides how the item at target is restored, based on whether it
+// already exists and res.Overwrite. For ActionSkipped, the existing item is
+// kept as is. Whether the content of an existing file already matches the
+// snapshot is only determined while restoring the files, see
+// fileRestorer.reuseExisting.
+func (res *Restorer) itemAction(node *restic.Node, target string) restoreui.ItemAction {
+	fi, err := fs.Lstat(target)
+	if err != nil {
+		// errors other than a missing target are reported while restoring
+		return restoreui.ActionCreated
+	}
+
+	switch res.Overwrite {
+	case OverwriteNever:
+		return restoreui.ActionSkipped
+
+	case OverwriteIfNewer:
+		if !node.ModTime.After(fi.ModTime()) {
+			return restoreui.ActionSkipped
+		}
+
+	case OverwriteIfChanged:
+		switch node.Type {
+		case "file":
+			if !fi.Mode().IsRegular() || fi.Size() != int64(node.Size) {
+				break
+			}
+			if fi.ModTime().Equal(node.ModTime) {
+				return restoreui.ActionSkipped
+			}
+
+		case "symlink":
+			if fi.Mode()&os.ModeSymlink == 0 {
+				break
+			}
+			linkTarget, err := os.Readlink(target)
+			if err == nil && linkTarget == node.LinkTarget {
+				return restoreui.ActionSkipped
+			}
+		}
+	}
+
+	return restoreui.ActionOverwritten
+}
+
+// dirAction decides whether the metadata of the directory at target is
+// restored. The directory itself is always created if it is missing, for
+// ActionSkipped the metadata of the existing directory is kept.
+func (res *Restorer) dirAction(node *restic.Node, target string) restoreui.ItemAction {
+	fi, err := fs.Lstat(target)
+	if err != nil {
+		return restoreui.ActionCreated
+	}
+	if !fi.IsDir() {
+		return restoreui.ActionOverwritten
+	}
+
+	switch res.Overwrite {
+	case OverwriteNever:
+		return restoreui.ActionSkipped
+	case OverwriteIfNewer:
+		if !node.ModTime.After(fi.ModTime()) {
+			return restoreui.ActionSkipped
+		}
+	}
+
+	return restoreui.ActionMetadataUpdated
+}
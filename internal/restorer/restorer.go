@@ -1,7 +1,9 @@
 package restorer
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"os"
 	"path/filepath"
 	"sync/atomic"
@@ -26,6 +28,22 @@ type Restorer struct {
 	Error        func(location string, err error) error
 	Warn         func(message string)
 	SelectFilter func(item string, dstpath string, node *restic.Node) (selectedForRestore bool, childMayBeSelected bool)
+	// SelectXattr selects the extended attributes which are restored. If
+	// nil, all extended attributes are restored.
+	SelectXattr restic.XattrSelectFunc
+	// Overwrite controls which items already existing in the target
+	// directory are restored again.
+	Overwrite OverwriteBehavior
+	// Delete removes the items in the target directory which do not exist in
+	// the snapshot. Items which are not selected by SelectFilter are kept.
+	Delete bool
+	// DryRun only determines which items would be restored or deleted, the
+	// target directory is not modified.
+	DryRun bool
+
+	// actions contains the action taken by RestoreTo for each target path.
+	// Skipped items are not checked by VerifyFiles.
+	actions map[string]restoreui.ItemAction
 }
 
 var restorerAbortOnAllErrors = func(_ string, err error) error { return err }
@@ -49,6 +67,9 @@ type treeVisitor struct {
 	enterDir  func(node *restic.Node, target, location string) error
 	visitNode func(node *restic.Node, target, location string) error
 	leaveDir  func(node *restic.Node, target, location string) error
+	// visitExtraneous is called for the selected items in a target directory
+	// which do not exist in the tree.
+	visitExtraneous func(target, location string) error
 }
 
 // traverseTree traverses a tree from the repo and calls treeVisitor.
@@ -61,6 +82,13 @@ func (res *Restorer) traverseTree(ctx context.Context, target, location string,
 		return hasRestored, res.Error(location, err)
 	}
 
+	if visitor.visitExtraneous != nil {
+		err = res.visitExtraneous(tree, target, location, visitor.visitExtraneous)
+		if err != nil {
+			return hasRestored, err
+		}
+	}
+
 	for _, node := range tree.Nodes {
 
 		// ensure that the node name does not contain anything that refers to a
@@ -179,7 +207,7 @@ func (res *Restorer) restoreNodeTo(ctx context.Context, node *restic.Node, targe
 
 func (res *Restorer) restoreNodeMetadataTo(node *restic.Node, target, location string) error {
 	debug.Log("restoreNodeMetadata %v %v %v", node.Name, target, location)
-	err := node.RestoreMetadata(target, res.Warn)
+	err := node.RestoreMetadata(target, res.Warn, res.SelectXattr)
 	if err != nil {
 		debug.Log("node.RestoreMetadata(%s) error %v", target, err)
 	}
@@ -187,7 +215,7 @@ func (res *Restorer) restoreNodeMetadataTo(node *restic.Node, target, location s
 }
 
 func (res *Restorer) restoreHardlinkAt(node *restic.Node, target, path, location string) error {
-	if err := fs.Remove(path); !os.IsNotExist(err) {
+	if err := fs.Remove(path); err != nil && !os.IsNotExist(err) {
 		return errors.Wrap(err, "RemoveCreateHardlink")
 	}
 	err := fs.Link(target, path)
@@ -240,18 +268,38 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 	}
 
 	idx := NewHardlinkIndex[string]()
+	res.actions = make(map[string]restoreui.ItemAction)
 	filerestorer := newFileRestorer(dst, res.repo.LoadBlobsFromPack, res.repo.Index().Lookup,
-		res.repo.Connections(), res.sparse, res.progress)
+		res.repo.Connections(), res.sparse, res.repo.Config().ChunkerPolynomial, res.progress)
 	filerestorer.Error = res.Error
 
+	var visitExtraneous func(target, location string) error
+	if res.Delete {
+		visitExtraneous = res.removeExtraneous
+	}
+
+	// blobs which are downloaded for the files to restore, only used for a dry run
+	downloaded := restic.NewIDSet()
+
 	debug.Log("first pass for %q", dst)
 
 	// first tree pass: create directories and collect all files to restore
 	_, err = res.traverseTree(ctx, dst, string(filepath.Separator), *res.sn.Tree, treeVisitor{
-		enterDir: func(_ *restic.Node, target, location string) error {
+		enterDir: func(node *restic.Node, target, location string) error {
 			debug.Log("first pass, enterDir: mkdir %q, leaveDir should restore metadata", location)
+			action := res.dirAction(node, target)
+			res.actions[target] = action
 			if res.progress != nil {
-				res.progress.AddFile(0)
+				if action == restoreui.ActionSkipped {
+					res.progress.AddSkippedFile(0)
+				} else {
+					res.progress.AddFile(0)
+				}
+			}
+
+			if res.DryRun {
+				res.reportItem(target, location, 0)
+				return nil
 			}
 			// create dir with default permissions
 			// #leaveDir restores dir metadata after visiting all children
@@ -260,11 +308,31 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 
 		visitNode: func(node *restic.Node, target, location string) error {
 			debug.Log("first pass, visitNode: mkdir %q, leaveDir on second pass should restore metadata", location)
-			// create parent dir with default permissions
-			// second pass #leaveDir restores dir metadata after visiting/restoring all children
-			err := fs.MkdirAll(filepath.Dir(target), 0700)
-			if err != nil {
-				return err
+			if !res.DryRun {
+				// create parent dir with default permissions
+				// second pass #leaveDir restores dir metadata after visiting/restoring all children
+				err := fs.MkdirAll(filepath.Dir(target), 0700)
+				if err != nil {
+					return err
+				}
+			}
+
+			action := res.itemAction(node, target)
+			res.actions[target] = action
+			if action == restoreui.ActionSkipped {
+				debug.Log("first pass, visitNode: keeping existing %q", location)
+				if res.progress != nil {
+					res.progress.AddSkippedFile(node.Size)
+				}
+				// skipped items are not visited in the second pass
+				res.reportItem(target, location, node.Size)
+				return nil
+			}
+
+			if res.DryRun {
+				// the action for an existing file may change once its
+				// content has been compared with the snapshot
+				defer res.reportItem(target, location, node.Size)
 			}
 
 			if node.Type != "file" {
@@ -296,53 +364,176 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 				res.progress.AddFile(node.Size)
 			}
 
-			filerestorer.addFile(location, node.Content, int64(node.Size))
+			if res.DryRun {
+				if res.addDownload(target, action == restoreui.ActionOverwritten, node, downloaded) {
+					res.actions[target] = restoreui.ActionMetadataUpdated
+				}
+				return nil
+			}
+
+			filerestorer.addFile(location, node.Content, int64(node.Size), node.ModTime, action == restoreui.ActionOverwritten)
 
 			return nil
 		},
+		visitExtraneous: visitExtraneous,
 	})
 	if err != nil {
 		return err
 	}
 
+	if res.DryRun {
+		return nil
+	}
+
 	err = filerestorer.restoreFiles(ctx)
 	if err != nil {
 		return err
 	}
 
+	for _, file := range filerestorer.files {
+		if file.unchanged {
+			res.actions[filerestorer.targetPath(file.location)] = restoreui.ActionMetadataUpdated
+		}
+	}
+
 	debug.Log("second pass for %q", dst)
 
+	// inode flags like immutable prevent any further modification, so they
+	// are only set once everything else has been restored
+	var flagged []inodeFlagsItem
+
+	restoreNode := func(node *restic.Node, target, location string) error {
+		debug.Log("second pass, visitNode: restore node %q", location)
+		if _, ok := node.GenericAttributes[restic.TypeLinuxFlags]; ok {
+			flagged = append(flagged, inodeFlagsItem{node, target, location})
+		}
+		if node.Type != "file" {
+			return res.restoreNodeTo(ctx, node, target, location)
+		}
+
+		// create empty files, but not hardlinks to empty files
+		if node.Size == 0 && (node.Links < 2 || !idx.Has(node.Inode, node.DeviceID)) {
+			if node.Links > 1 {
+				idx.Add(node.Inode, node.DeviceID, location)
+			}
+			return res.restoreEmptyFileAt(node, target, location)
+		}
+
+		if idx.Has(node.Inode, node.DeviceID) && idx.Value(node.Inode, node.DeviceID) != location {
+			return res.restoreHardlinkAt(node, filerestorer.targetPath(idx.Value(node.Inode, node.DeviceID)), target, location)
+		}
+
+		return res.restoreNodeMetadataTo(node, target, location)
+	}
+
 	// second tree pass: restore special files and filesystem metadata
 	_, err = res.traverseTree(ctx, dst, string(filepath.Separator), *res.sn.Tree, treeVisitor{
 		visitNode: func(node *restic.Node, target, location string) error {
-			debug.Log("second pass, visitNode: restore node %q", location)
-			if node.Type != "file" {
-				return res.restoreNodeTo(ctx, node, target, location)
-			}
-
-			// create empty files, but not hardlinks to empty files
-			if node.Size == 0 && (node.Links < 2 || !idx.Has(node.Inode, node.DeviceID)) {
-				if node.Links > 1 {
-					idx.Add(node.Inode, node.DeviceID, location)
-				}
-				return res.restoreEmptyFileAt(node, target, location)
+			if res.actions[target] == restoreui.ActionSkipped {
+				return nil
 			}
 
-			if idx.Has(node.Inode, node.DeviceID) && idx.Value(node.Inode, node.DeviceID) != location {
-				return res.restoreHardlinkAt(node, filerestorer.targetPath(idx.Value(node.Inode, node.DeviceID)), target, location)
+			err := restoreNode(node, target, location)
+			if err == nil {
+				res.reportItem(target, location, node.Size)
 			}
-
-			return res.restoreNodeMetadataTo(node, target, location)
+			return err
 		},
 		leaveDir: func(node *restic.Node, target, location string) error {
+			if res.actions[target] == restoreui.ActionSkipped {
+				res.reportItem(target, location, 0)
+				return nil
+			}
+
+			if _, ok := node.GenericAttributes[restic.TypeLinuxFlags]; ok {
+				flagged = append(flagged, inodeFlagsItem{node, target, location})
+			}
 			err := res.restoreNodeMetadataTo(node, target, location)
 			if err == nil && res.progress != nil {
 				res.progress.AddProgress(location, 0, 0)
+				res.reportItem(target, location, 0)
 			}
 			return err
 		},
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	return res.restoreInodeFlags(ctx, flagged)
+}
+
+// reportItem reports the action for the item at target, which was determined
+// in the first pass of RestoreTo.
+func (res *Restorer) reportItem(target, location string, size uint64) {
+	if res.progress == nil {
+		return
+	}
+	if action, ok := res.actions[target]; ok {
+		res.progress.CompleteItem(action, location, size)
+	}
+}
+
+// addDownload records the size of the blobs of node which must be
+// downloaded and have not been seen before. For an existing file, blobs
+// whose content is found in the file are not downloaded. It returns true if
+// the existing file already has the content of the snapshot.
+func (res *Restorer) addDownload(target string, existing bool, node *restic.Node, seen restic.IDSet) bool {
+	if res.progress == nil {
+		return false
+	}
+
+	var local restic.IDSet
+	var unchanged bool
+	if existing {
+		// reading all files is expensive, so like backup assume that a file
+		// with the size and modification time of node has its content
+		fi, err := fs.Lstat(target)
+		if err == nil && fi.Mode().IsRegular() && fi.Size() == int64(node.Size) && fi.ModTime().Equal(node.ModTime) {
+			return true
+		}
+
+		local, unchanged, err = res.existingBlobs(target, node)
+		if err != nil {
+			debug.Log("unable to read existing file %v: %v", target, err)
+		}
+	}
+
+	for _, id := range node.Content {
+		if local.Has(id) || seen.Has(id) {
+			continue
+		}
+		seen.Insert(id)
+
+		// missing blobs are only detected when actually restoring
+		if pbs := res.repo.Index().Lookup(restic.BlobHandle{ID: id, Type: restic.DataBlob}); len(pbs) > 0 {
+			res.progress.AddDownload(uint64(pbs[0].Length))
+		}
+	}
+	return unchanged
+}
+
+type inodeFlagsItem struct {
+	node     *restic.Node
+	target   string
+	location string
+}
+
+// restoreInodeFlags sets the inode flags for all items.
+func (res *Restorer) restoreInodeFlags(ctx context.Context, items []inodeFlagsItem) error {
+	for _, item := range items {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		debug.Log("restoring inode flags for %q", item.location)
+		if err := item.node.RestoreInodeFlags(item.target); err != nil {
+			if err := res.Error(item.location, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // Snapshot returns the snapshot this restorer is configured to use.
@@ -353,6 +544,10 @@ func (res *Restorer) Snapshot() *restic.Snapshot {
 // Number of workers in VerifyFiles.
 const nVerifyWorkers = 8
 
+// verifyFileHashBufferSize is the size of the buffer used to read files when
+// verifying the whole-file checksum.
+const verifyFileHashBufferSize = 1 << 20
+
 // VerifyFiles checks whether all regular files in the snapshot res.sn
 // have been successfully written to dst. It stops when it encounters an
 // error. It returns that error and the number of files it has successfully
@@ -379,6 +574,9 @@ func (res *Restorer) VerifyFiles(ctx context.Context, dst string) (int, error) {
 				if node.Type != "file" {
 					return nil
 				}
+				if res.actions[target] == restoreui.ActionSkipped {
+					return nil
+				}
 				select {
 				case <-ctx.Done():
 					return ctx.Err()
@@ -433,6 +631,10 @@ func (res *Restorer) verifyFile(target string, node *restic.Node, buf []byte) ([
 			target, node.Size, fi.Size())
 	}
 
+	if node.FileHash != "" {
+		return res.verifyFileHash(f, target, node, buf)
+	}
+
 	var offset int64
 	for _, blobID := range node.Content {
 		length, found := res.repo.LookupBlobSize(blobID, restic.DataBlob)
@@ -440,16 +642,11 @@ func (res *Restorer) verifyFile(target string, node *restic.Node, buf []byte) ([
 			return buf, errors.Errorf("Unable to fetch blob %s", blobID)
 		}
 
-		if length > uint(cap(buf)) {
-			buf = make([]byte, 2*length)
-		}
-		buf = buf[:length]
-
-		_, err = f.ReadAt(buf, offset)
+		found, buf, err = hasBlobAt(f, blobID, offset, length, buf)
 		if err != nil {
 			return buf, err
 		}
-		if !blobID.Equal(restic.Hash(buf)) {
+		if !found {
 			return buf, errors.Errorf(
 				"Unexpected content in %s, starting at offset %d",
 				target, offset)
@@ -459,3 +656,30 @@ func (res *Restorer) verifyFile(target string, node *restic.Node, buf []byte) ([
 
 	return buf, nil
 }
+
+// verifyFileHash checks that the checksum of the content of f matches the
+// file hash stored in node. This does not require accessing the index.
+func (res *Restorer) verifyFileHash(f *os.File, target string, node *restic.Node, buf []byte) ([]byte, error) {
+	algo, sum, err := restic.ParseFileHash(node.FileHash)
+	if err != nil {
+		return buf, err
+	}
+	h, err := restic.NewFileHash(algo)
+	if err != nil {
+		return buf, err
+	}
+
+	if cap(buf) < verifyFileHashBufferSize {
+		buf = make([]byte, verifyFileHashBufferSize)
+	}
+	buf = buf[:cap(buf)]
+
+	if _, err := io.CopyBuffer(h, f, buf); err != nil {
+		return buf, err
+	}
+	if !bytes.Equal(h.Sum(nil), sum) {
+		return buf, errors.Errorf("Unexpected content in %s, %s checksum does not match", target, algo)
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,55 @@
+package restorer
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestRestorerInodeFlags(t *testing.T) {
+	tempdir := rtest.TempDir(t)
+
+	// check that the file system supports inode flags
+	rtest.OK(t, fs.SetInodeFlags(tempdir, fs.InodeFlagNoDump))
+	if flags, err := fs.GetInodeFlags(tempdir); err != nil || flags&fs.InodeFlagNoDump == 0 {
+		t.Skipf("file system does not support inode flags: %v", err)
+	}
+	rtest.OK(t, fs.SetInodeFlags(tempdir, 0))
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"dir": Dir{
+				Nodes: map[string]Node{
+					"file":  File{Data: "content: file\n"},
+					"empty": File{},
+					"link1": File{Data: "content: link\n", Links: 2, Inode: 42},
+					"link2": File{Data: "content: link\n", Links: 2, Inode: 42},
+				},
+			},
+		},
+	}, func(_ *FileAttributes, _ bool) map[restic.GenericAttributeType]json.RawMessage {
+		return map[restic.GenericAttributeType]json.RawMessage{
+			restic.TypeLinuxFlags: json.RawMessage(strconv.Itoa(fs.InodeFlagNoDump)),
+		}
+	})
+
+	res := NewRestorer(repo, sn, false, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+
+	for _, item := range []string{"dir", "dir/file", "dir/empty", "dir/link1", "dir/link2"} {
+		flags, err := fs.GetInodeFlags(filepath.Join(tempdir, filepath.FromSlash(item)))
+		rtest.OK(t, err)
+		rtest.Assert(t, flags&fs.InodeFlagNoDump != 0, "nodump flag not restored for %v", item)
+	}
+}
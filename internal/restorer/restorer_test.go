@@ -3,7 +3,9 @@ package restorer
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
 	"io"
 	"math"
 	"os"
@@ -14,10 +16,12 @@ import (
 	"time"
 
 	"github.com/restic/restic/internal/archiver"
+	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/fs"
 	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
+	restoreui "github.com/restic/restic/internal/ui/restore"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -809,6 +813,264 @@ func TestRestorerConsistentTimestampsAndPermissions(t *testing.T) {
 	}
 }
 
+func TestRestorerOverwrite(t *testing.T) {
+	snapshotTime := time.Date(2019, time.January, 9, 1, 46, 40, 0, time.UTC)
+	olderTime := snapshotTime.Add(-time.Hour)
+	newerTime := snapshotTime.Add(time.Hour)
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"foo": File{Data: "content: foo\n", ModTime: snapshotTime},
+			"bar": File{Data: "content: bar\n", ModTime: snapshotTime},
+			"baz": File{Data: "content: baz\n", ModTime: snapshotTime},
+			"new": File{Data: "content: new\n", ModTime: snapshotTime},
+		},
+	}, noopGetGenericAttributes)
+
+	type file struct {
+		content string
+		modTime time.Time
+	}
+	existing := map[string]file{
+		// same size and modification time, but different content
+		"foo": {"CONTENT: FOO\n", snapshotTime},
+		"bar": {"old bar\n", olderTime},
+		// same content, but newer
+		"baz": {"content: baz\n", newerTime},
+	}
+
+	for _, test := range []struct {
+		overwrite OverwriteBehavior
+		expected  map[string]file
+	}{
+		{OverwriteAlways, map[string]file{
+			"foo": {"content: foo\n", snapshotTime},
+			"bar": {"content: bar\n", snapshotTime},
+			"baz": {"content: baz\n", snapshotTime},
+		}},
+		{OverwriteIfChanged, map[string]file{
+			"foo": {"CONTENT: FOO\n", snapshotTime},
+			"bar": {"content: bar\n", snapshotTime},
+			"baz": {"content: baz\n", snapshotTime},
+		}},
+		{OverwriteIfNewer, map[string]file{
+			"foo": {"CONTENT: FOO\n", snapshotTime},
+			"bar": {"content: bar\n", snapshotTime},
+			"baz": {"content: baz\n", newerTime},
+		}},
+		{OverwriteNever, existing},
+	} {
+		t.Run(test.overwrite.String(), func(t *testing.T) {
+			tempdir := rtest.TempDir(t)
+			for name, f := range existing {
+				filename := filepath.Join(tempdir, name)
+				rtest.OK(t, os.WriteFile(filename, []byte(f.content), 0644))
+				rtest.OK(t, os.Chtimes(filename, f.modTime, f.modTime))
+			}
+
+			res := NewRestorer(repo, sn, false, nil)
+			res.Overwrite = test.overwrite
+			rtest.OK(t, res.RestoreTo(context.TODO(), tempdir))
+
+			expected := map[string]file{"new": {"content: new\n", snapshotTime}}
+			for name, f := range test.expected {
+				expected[name] = f
+			}
+			for name, f := range expected {
+				filename := filepath.Join(tempdir, name)
+				data, err := os.ReadFile(filename)
+				rtest.OK(t, err)
+				rtest.Equals(t, f.content, string(data), name)
+
+				fi, err := os.Stat(filename)
+				rtest.OK(t, err)
+				rtest.Assert(t, fi.ModTime().Equal(f.modTime), "%v: unexpected modification time %v, expected %v", name, fi.ModTime(), f.modTime)
+			}
+
+			// existing files which were kept are not verified
+			_, err := res.VerifyFiles(context.TODO(), tempdir)
+			rtest.OK(t, err)
+		})
+	}
+}
+
+func TestOverwriteBehaviorSet(t *testing.T) {
+	for _, name := range []string{"always", "if-changed", "if-newer", "never"} {
+		var b OverwriteBehavior
+		rtest.OK(t, b.Set(name))
+		rtest.Equals(t, name, b.String())
+	}
+
+	var b OverwriteBehavior
+	rtest.Assert(t, b.Set("sometimes") != nil, "missing error for invalid behavior")
+}
+
+func TestRestorerDelete(t *testing.T) {
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"dir": Dir{Nodes: map[string]Node{
+				"file": File{Data: "content: file\n"},
+			}},
+			"foo": File{Data: "content: foo\n"},
+		},
+	}, noopGetGenericAttributes)
+
+	for _, dryRun := range []bool{false, true} {
+		t.Run(fmt.Sprintf("dry-run-%v", dryRun), func(t *testing.T) {
+			tempdir := rtest.TempDir(t)
+			for _, name := range []string{"extra", "excluded", "dir/file", "dir/extra/file"} {
+				filename := filepath.Join(tempdir, filepath.FromSlash(name))
+				rtest.OK(t, os.MkdirAll(filepath.Dir(filename), 0755))
+				rtest.OK(t, os.WriteFile(filename, []byte("old\n"), 0644))
+			}
+
+			res := NewRestorer(repo, sn, false, nil)
+			res.Delete = true
+			res.DryRun = dryRun
+			res.SelectFilter = func(item string, _ string, node *restic.Node) (bool, bool) {
+				selected := item != "/excluded"
+				return selected, selected && node.Type == "dir"
+			}
+			rtest.OK(t, res.RestoreTo(context.TODO(), tempdir))
+
+			// excluded items are never deleted
+			expected := map[string]bool{
+				"excluded":       true,
+				"extra":          dryRun,
+				"dir/extra":      dryRun,
+				"dir/extra/file": dryRun,
+				"dir/file":       true,
+				"foo":            !dryRun,
+			}
+			for name, exists := range expected {
+				_, err := os.Lstat(filepath.Join(tempdir, filepath.FromSlash(name)))
+				if exists {
+					rtest.OK(t, err)
+				} else {
+					rtest.Assert(t, errors.Is(err, os.ErrNotExist), "%v was not deleted: %v", name, err)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(tempdir, "dir", "file"))
+			rtest.OK(t, err)
+			if dryRun {
+				rtest.Equals(t, "old\n", string(data))
+			} else {
+				rtest.Equals(t, "content: file\n", string(data))
+			}
+		})
+	}
+}
+
+type actionRecorder struct {
+	actions map[string]restoreui.ItemAction
+	state   restoreui.State
+}
+
+func (p *actionRecorder) Update(_ restoreui.State, _ time.Duration) {
+}
+func (p *actionRecorder) CompleteItem(action restoreui.ItemAction, item string, _ uint64) {
+	p.actions[item] = action
+}
+func (p *actionRecorder) Finish(s restoreui.State, _ time.Duration) {
+	p.state = s
+}
+
+func TestRestorerDryRun(t *testing.T) {
+	snapshotTime := time.Date(2019, time.January, 9, 1, 46, 40, 0, time.UTC)
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"dir": Dir{ModTime: snapshotTime, Nodes: map[string]Node{
+				"unchanged": File{Data: "content: unchanged\n", ModTime: snapshotTime},
+				"copied":    File{Data: "content: copied\n", ModTime: snapshotTime},
+			}},
+			"changed": File{Data: "content: changed\n", ModTime: snapshotTime},
+			"new":     File{Data: "content: new\n", ModTime: snapshotTime},
+		},
+	}, noopGetGenericAttributes)
+
+	tempdir := rtest.TempDir(t)
+	for name, content := range map[string]string{
+		"dir/unchanged": "content: unchanged\n",
+		"dir/copied":    "content: copied\n",
+		"changed":       "old\n",
+		"extra":         "extra\n",
+	} {
+		filename := filepath.Join(tempdir, filepath.FromSlash(name))
+		rtest.OK(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		rtest.OK(t, os.WriteFile(filename, []byte(content), 0644))
+	}
+	rtest.OK(t, os.Chtimes(filepath.Join(tempdir, "dir", "unchanged"), snapshotTime, snapshotTime))
+
+	expected := map[string]restoreui.ItemAction{
+		"/dir":           restoreui.ActionMetadataUpdated,
+		"/dir/unchanged": restoreui.ActionSkipped,
+		"/dir/copied":    restoreui.ActionMetadataUpdated,
+		"/changed":       restoreui.ActionOverwritten,
+		"/new":           restoreui.ActionCreated,
+		"/extra":         restoreui.ActionDeleted,
+	}
+
+	// the dry run must report the same actions as the actual restore
+	for _, dryRun := range []bool{true, false} {
+		recorder := &actionRecorder{actions: make(map[string]restoreui.ItemAction)}
+		progress := restoreui.NewProgress(recorder, 0)
+		res := NewRestorer(repo, sn, false, progress)
+		res.Overwrite = OverwriteIfChanged
+		res.Delete = true
+		res.DryRun = dryRun
+		rtest.OK(t, res.RestoreTo(context.TODO(), tempdir))
+		progress.Finish()
+
+		rtest.Equals(t, expected, recorder.actions)
+		rtest.Equals(t, uint64(1), recorder.state.FilesDeleted)
+		rtest.Equals(t, uint64(1), recorder.state.FilesSkipped)
+
+		if dryRun {
+			// only the blobs of "changed" and "new" must be downloaded
+			rtest.Assert(t, recorder.state.BytesToDownload > 0, "missing size of data to download")
+			rtest.Equals(t, uint64(0), recorder.state.FilesFinished)
+
+			data, err := os.ReadFile(filepath.Join(tempdir, "changed"))
+			rtest.OK(t, err)
+			rtest.Equals(t, "old\n", string(data))
+			_, err = os.Lstat(filepath.Join(tempdir, "new"))
+			rtest.Assert(t, errors.Is(err, os.ErrNotExist), "dry run created file: %v", err)
+		}
+	}
+}
+
+func TestRestorerDryRunSizeModTime(t *testing.T) {
+	snapshotTime := time.Date(2019, time.January, 9, 1, 46, 40, 0, time.UTC)
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"file": File{Data: "content: file\n", ModTime: snapshotTime},
+		},
+	}, noopGetGenericAttributes)
+
+	// a file with the same size and modification time is not read
+	tempdir := rtest.TempDir(t)
+	filename := filepath.Join(tempdir, "file")
+	rtest.OK(t, os.WriteFile(filename, []byte("content: elif\n"), 0644))
+	rtest.OK(t, os.Chtimes(filename, snapshotTime, snapshotTime))
+
+	recorder := &actionRecorder{actions: make(map[string]restoreui.ItemAction)}
+	progress := restoreui.NewProgress(recorder, 0)
+	res := NewRestorer(repo, sn, false, progress)
+	res.DryRun = true
+	rtest.OK(t, res.RestoreTo(context.TODO(), tempdir))
+	progress.Finish()
+
+	rtest.Equals(t, restoreui.ActionMetadataUpdated, recorder.actions["/file"])
+	rtest.Equals(t, uint64(0), recorder.state.BytesToDownload)
+}
+
 // VerifyFiles must not report cancellation of its context through res.Error.
 func TestVerifyCancel(t *testing.T) {
 	snapshot := Snapshot{
@@ -843,6 +1105,31 @@ func TestVerifyCancel(t *testing.T) {
 	rtest.Assert(t, strings.Contains(errs[0].Error(), "Invalid file size for"), "wrong error %q", errs[0].Error())
 }
 
+func TestVerifyFileHash(t *testing.T) {
+	data := []byte("content: foo\n")
+	sum := sha256.Sum256(data)
+	node := &restic.Node{
+		Name:     "foo",
+		Type:     "file",
+		Size:     uint64(len(data)),
+		FileHash: restic.FormatFileHash(restic.FileHashSHA256, sum[:]),
+	}
+
+	// the content is checked using only the file hash, so no repository
+	// is needed
+	res := &Restorer{}
+
+	filename := filepath.Join(rtest.TempDir(t), "foo")
+	rtest.OK(t, os.WriteFile(filename, data, 0644))
+	_, err := res.verifyFile(filename, node, nil)
+	rtest.OK(t, err)
+
+	rtest.OK(t, os.WriteFile(filename, []byte("content: bar\n"), 0644))
+	_, err = res.verifyFile(filename, node, nil)
+	rtest.Assert(t, err != nil, "nil error for modified content")
+	rtest.Assert(t, strings.Contains(err.Error(), "Unexpected content in"), "wrong error %q", err.Error())
+}
+
 func TestRestorerSparseFiles(t *testing.T) {
 	repo := repository.TestRepository(t)
 
@@ -858,7 +1145,7 @@ func TestRestorerSparseFiles(t *testing.T) {
 	rtest.OK(t, err)
 
 	arch := archiver.New(repo, target, archiver.Options{})
-	sn, _, err := arch.Snapshot(context.Background(), []string{"/zeros"},
+	sn, _, _, err := arch.Snapshot(context.Background(), []string{"/zeros"},
 		archiver.SnapshotOptions{})
 	rtest.OK(t, err)
 
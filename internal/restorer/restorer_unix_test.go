@@ -70,16 +70,15 @@ func getBlockCount(t *testing.T, filename string) int64 {
 }
 
 type printerMock struct {
-	filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64
+	s restoreui.State
 }
 
-func (p *printerMock) Update(_, _, _, _ uint64, _ time.Duration) {
+func (p *printerMock) Update(_ restoreui.State, _ time.Duration) {
 }
-func (p *printerMock) Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, _ time.Duration) {
-	p.filesFinished = filesFinished
-	p.filesTotal = filesTotal
-	p.allBytesWritten = allBytesWritten
-	p.allBytesTotal = allBytesTotal
+func (p *printerMock) CompleteItem(_ restoreui.ItemAction, _ string, _ uint64) {
+}
+func (p *printerMock) Finish(s restoreui.State, _ time.Duration) {
+	p.s = s
 }
 
 func TestRestorerProgressBar(t *testing.T) {
@@ -116,8 +115,64 @@ func TestRestorerProgressBar(t *testing.T) {
 	const filesTotal = filesFinished
 	const allBytesWritten = 10
 	const allBytesTotal = allBytesWritten
-	rtest.Assert(t, mock.filesFinished == filesFinished, "filesFinished: expected %v, got %v", filesFinished, mock.filesFinished)
-	rtest.Assert(t, mock.filesTotal == filesTotal, "filesTotal: expected %v, got %v", filesTotal, mock.filesTotal)
-	rtest.Assert(t, mock.allBytesWritten == allBytesWritten, "allBytesWritten: expected %v, got %v", allBytesWritten, mock.allBytesWritten)
-	rtest.Assert(t, mock.allBytesTotal == allBytesTotal, "allBytesTotal: expected %v, got %v", allBytesTotal, mock.allBytesTotal)
+	rtest.Assert(t, mock.s.FilesFinished == filesFinished, "filesFinished: expected %v, got %v", filesFinished, mock.s.FilesFinished)
+	rtest.Assert(t, mock.s.FilesTotal == filesTotal, "filesTotal: expected %v, got %v", filesTotal, mock.s.FilesTotal)
+	rtest.Assert(t, mock.s.AllBytesWritten == allBytesWritten, "allBytesWritten: expected %v, got %v", allBytesWritten, mock.s.AllBytesWritten)
+	rtest.Assert(t, mock.s.AllBytesTotal == allBytesTotal, "allBytesTotal: expected %v, got %v", allBytesTotal, mock.s.AllBytesTotal)
+
+	// restoring again only restores the metadata, the content of the
+	// existing files already matches the snapshot
+	recorder := &actionRecorder{actions: make(map[string]restoreui.ItemAction)}
+	progress = restoreui.NewProgress(recorder, 0)
+	res = NewRestorer(repo, sn, false, progress)
+	res.Overwrite = OverwriteIfChanged
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+	progress.Finish()
+
+	rtest.Equals(t, map[string]restoreui.ItemAction{
+		"/dirtest":       restoreui.ActionMetadataUpdated,
+		"/dirtest/file1": restoreui.ActionMetadataUpdated,
+		"/dirtest/file2": restoreui.ActionOverwritten,
+		"/file2":         restoreui.ActionMetadataUpdated,
+	}, recorder.actions)
+	rtest.Equals(t, uint64(filesFinished), recorder.state.FilesFinished)
+	rtest.Equals(t, uint64(0), recorder.state.FilesSkipped)
+}
+
+func TestRestorerOverwriteDirMetadata(t *testing.T) {
+	snapshotTime := time.Date(2019, time.January, 9, 1, 46, 40, 0, time.UTC)
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, Snapshot{
+		Nodes: map[string]Node{
+			"dir": Dir{Mode: 0750, ModTime: snapshotTime},
+		},
+	}, noopGetGenericAttributes)
+
+	for _, test := range []struct {
+		overwrite OverwriteBehavior
+		modTime   time.Time
+		mode      os.FileMode
+	}{
+		{OverwriteAlways, snapshotTime.Add(time.Hour), 0750},
+		{OverwriteIfChanged, snapshotTime.Add(time.Hour), 0750},
+		{OverwriteIfNewer, snapshotTime.Add(-time.Hour), 0750},
+		{OverwriteIfNewer, snapshotTime.Add(time.Hour), 0700},
+		{OverwriteNever, snapshotTime.Add(-time.Hour), 0700},
+	} {
+		t.Run(test.overwrite.String(), func(t *testing.T) {
+			tempdir := rtest.TempDir(t)
+			dir := filepath.Join(tempdir, "dir")
+			rtest.OK(t, os.Mkdir(dir, 0700))
+			rtest.OK(t, os.Chtimes(dir, test.modTime, test.modTime))
+
+			res := NewRestorer(repo, sn, false, nil)
+			res.Overwrite = test.overwrite
+			rtest.OK(t, res.RestoreTo(context.TODO(), tempdir))
+
+			fi, err := os.Stat(dir)
+			rtest.OK(t, err)
+			rtest.Equals(t, test.mode, fi.Mode().Perm())
+		})
+	}
 }
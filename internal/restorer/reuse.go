@@ -0,0 +1,348 @@
+package restorer
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/restic/chunker"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/restic"
+)
+
+// localChunks splits the content of rd into chunks using the chunker
+// polynomial pol, which must be the one of the repository. It returns the
+// offsets at which the chunks with the same ID as a blob in the repository
+// are found. buf must have a capacity of at least chunker.MaxSize.
+func localChunks(rd io.Reader, pol chunker.Pol, buf []byte) (map[restic.ID][]int64, error) {
+	chunks := make(map[restic.ID][]int64)
+	chnker := chunker.New(rd, pol)
+	for {
+		chunk, err := chnker.Next(buf)
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id := restic.Hash(chunk.Data)
+		chunks[id] = append(chunks[id], int64(chunk.Start))
+	}
+}
+
+// mayContainMovedBlobs returns whether it is worth splitting an existing file
+// with the file info fi into chunks, to find blobs of the restored file with
+// the given size and modification time which are not at their offset.
+func mayContainMovedBlobs(fi os.FileInfo, size int64, modTime time.Time) bool {
+	if fi.Size() < chunker.MinSize {
+		// the chunker returns the whole file as a single chunk, downloading
+		// such a small file is cheap anyway
+		return false
+	}
+
+	// The file was either restored completely before or modified in place,
+	// neither of which moves data within the file.
+	return fi.Size() != size || !fi.ModTime().Equal(modTime)
+}
+
+// blobMove copies the content of a blob from one of the offsets in src to
+// the offset of the blob with index idx in the restored file.
+type blobMove struct {
+	idx int
+	src []int64
+}
+
+// hasBlobAt returns whether f contains the blob id with the given length at
+// offset. The buffer, possibly enlarged, is returned for reuse.
+func hasBlobAt(f *os.File, id restic.ID, offset int64, length uint, buf []byte) (bool, []byte, error) {
+	if length > uint(cap(buf)) {
+		buf = make([]byte, 2*length)
+	}
+	buf = buf[:length]
+
+	_, err := f.ReadAt(buf, offset)
+	if err == io.EOF {
+		// the file is too short
+		return false, buf, nil
+	}
+	if err != nil {
+		return false, buf, err
+	}
+	return id.Equal(restic.Hash(buf)), buf, nil
+}
+
+// reuseExistingFiles runs reuseExisting for all existing files in r.files,
+// using workerCount goroutines. The result contains the reused blobs for each
+// file, in the same order as r.files.
+func (r *fileRestorer) reuseExistingFiles(ctx context.Context) ([][]bool, error) {
+	reused := make([][]bool, len(r.files))
+
+	wg, ctx := errgroup.WithContext(ctx)
+	ch := make(chan int)
+
+	worker := func() error {
+		// each worker needs its own buffer of up to chunker.MaxSize bytes
+		var buf []byte
+		for i := range ch {
+			file := r.files[i]
+
+			var err error
+			reused[i], buf, err = r.reuseExisting(file, file.blobs.(restic.IDs), buf)
+			if err != nil {
+				debug.Log("unable to reuse content of %v: %v", file.location, err)
+				reused[i] = nil
+			}
+		}
+		return nil
+	}
+	for i := 0; i < r.workerCount; i++ {
+		wg.Go(worker)
+	}
+
+	wg.Go(func() error {
+		defer close(ch)
+		for i, file := range r.files {
+			if !file.existing {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ch <- i:
+			}
+		}
+		return nil
+	})
+
+	return reused, wg.Wait()
+}
+
+// reuseExisting tries to reuse the content of the existing target file
+// instead of downloading all blobs of the file. First, the blobs which are
+// already at their offset in the file are determined. This is the case for a
+// file whose restore was interrupted, such that the restore continues where it
+// stopped. If blobs are missing and mayContainMovedBlobs permits it, the
+// existing file is split into chunks and chunks which match a blob of the file
+// are moved in place to the offset of the blob. Afterwards the file is
+// truncated to its final size.
+//
+// The result contains for each blob whether its content is already in place.
+// If nothing can be reused, nil is returned and the file must be restored
+// from scratch. If the file already has the final content, file.unchanged is
+// set. buf is scratch space, it is returned for reuse.
+func (r *fileRestorer) reuseExisting(file *fileInfo, blobs restic.IDs, buf []byte) ([]bool, []byte, error) {
+	path := r.targetPath(file.location)
+
+	offsets := make([]int64, len(blobs))
+	lengths := make([]int64, len(blobs))
+	var offset int64
+	for i, id := range blobs {
+		packs := r.idx(restic.BlobHandle{ID: id, Type: restic.DataBlob})
+		if len(packs) == 0 {
+			// reported when restoring the file
+			return nil, buf, nil
+		}
+		offsets[i] = offset
+		lengths[i] = int64(packs[0].DataLength())
+		offset += lengths[i]
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if fs.IsAccessDenied(err) {
+		// the permissions are restored in the second pass
+		if err = fs.ResetPermissions(path); err != nil {
+			return nil, buf, err
+		}
+		f, err = os.OpenFile(path, os.O_RDWR, 0)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, buf, nil
+	}
+	if err != nil {
+		return nil, buf, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	if cap(buf) < chunker.MaxSize {
+		buf = make([]byte, chunker.MaxSize)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, buf, err
+	}
+
+	reused := make([]bool, len(blobs))
+	found, missing := false, false
+	for i, id := range blobs {
+		reused[i], buf, err = hasBlobAt(f, id, offsets[i], uint(lengths[i]), buf)
+		if err != nil {
+			return nil, buf, err
+		}
+		found = found || reused[i]
+		missing = missing || !reused[i]
+	}
+	file.unchanged = !missing && fi.Size() == file.size
+
+	var moves []blobMove
+	var forward int
+	if missing && mayContainMovedBlobs(fi, file.size, file.modTime) {
+		local, err := localChunks(f, r.chunkerPol, buf)
+		if err != nil {
+			return nil, buf, err
+		}
+
+		for i, id := range blobs {
+			src := local[id]
+			if reused[i] || len(src) == 0 {
+				continue
+			}
+			found = true
+
+			moves = append(moves, blobMove{idx: i, src: src})
+			if offsets[i] > src[0] {
+				forward++
+			}
+		}
+	}
+	if !found {
+		return nil, buf, nil
+	}
+
+	// The regions of the blobs in the restored file do not overlap, thus
+	// moving a blob never overwrites a blob which is already in place. It
+	// may however overwrite the source of another move. If the moves are
+	// processed in the order of their destination, all data written so far
+	// lies on one side of a watermark. A move is only done if its source lies
+	// on the other side, otherwise the blob is downloaded.
+	backward := forward*2 < len(moves)
+	if !backward {
+		// process from the end of the file if most blobs move towards it
+		for i, j := 0, len(moves)-1; i < j; i, j = i+1, j-1 {
+			moves[i], moves[j] = moves[j], moves[i]
+		}
+	}
+
+	watermark := int64(-1)
+	if !backward {
+		watermark = math.MaxInt64
+	}
+	for _, m := range moves {
+		dst, length := offsets[m.idx], lengths[m.idx]
+
+		src := int64(-1)
+		for _, o := range m.src {
+			if (backward && o >= watermark) || (!backward && o+length <= watermark) {
+				src = o
+				break
+			}
+		}
+
+		if src < 0 {
+			continue
+		}
+
+		data := buf[:length]
+		if _, err := f.ReadAt(data, src); err != nil {
+			return nil, buf, err
+		}
+		if !restic.Hash(data).Equal(blobs[m.idx]) {
+			// the file was modified concurrently
+			continue
+		}
+
+		if backward {
+			watermark = dst + length
+		} else {
+			watermark = dst
+		}
+		if _, err := f.WriteAt(data, dst); err != nil {
+			return nil, buf, err
+		}
+		reused[m.idx] = true
+	}
+
+	if err := f.Truncate(file.size); err != nil {
+		return nil, buf, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, buf, err
+	}
+
+	var reusedBytes int64
+	for i := range blobs {
+		if reused[i] {
+			reusedBytes += lengths[i]
+			if r.progress != nil {
+				r.progress.AddProgress(file.location, uint64(lengths[i]), uint64(file.size))
+			}
+		}
+	}
+	debug.Log("reusing %d of %d bytes of %v", reusedBytes, file.size, path)
+
+	return reused, buf, nil
+}
+
+// existingBlobs returns the blobs of content which the existing file at target
+// contains, either at their offset or elsewhere. This corresponds to the blobs
+// reused by fileRestorer.reuseExisting. The second result is true if the file
+// already has exactly the content of node.
+func (res *Restorer) existingBlobs(target string, node *restic.Node) (restic.IDSet, bool, error) {
+	f, err := os.Open(target)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+
+	existing := restic.NewIDSet()
+	missing := false
+	buf := make([]byte, chunker.MaxSize)
+	var offset int64
+	for _, id := range node.Content {
+		length, ok := res.repo.LookupBlobSize(id, restic.DataBlob)
+		if !ok {
+			missing = true
+			break
+		}
+
+		var found bool
+		found, buf, err = hasBlobAt(f, id, offset, length, buf)
+		if err != nil {
+			return nil, false, err
+		}
+		if found {
+			existing.Insert(id)
+		} else {
+			missing = true
+		}
+		offset += int64(length)
+	}
+
+	if missing && mayContainMovedBlobs(fi, int64(node.Size), node.ModTime) {
+		local, err := localChunks(f, res.repo.Config().ChunkerPolynomial, buf)
+		if err != nil {
+			return nil, false, err
+		}
+		for id := range local {
+			existing.Insert(id)
+		}
+	}
+
+	return existing, !missing && fi.Size() == offset, nil
+}
@@ -149,6 +149,15 @@ func (b *JSONProgress) CompleteItem(messageType, item string, s archiver.ItemSta
 	}
 }
 
+// ChangedDuringBackup reports a file which was modified while reading it.
+func (b *JSONProgress) ChangedDuringBackup(item string) {
+	b.print(verboseUpdate{
+		MessageType: "verbose_status",
+		Action:      "changed_during_backup",
+		Item:        item,
+	})
+}
+
 // ReportTotal sets the total stats up to now
 func (b *JSONProgress) ReportTotal(start time.Time, s archiver.ScanStats) {
 	if b.v >= 2 {
@@ -163,23 +172,45 @@ func (b *JSONProgress) ReportTotal(start time.Time, s archiver.ScanStats) {
 }
 
 // Finish prints the finishing messages.
-func (b *JSONProgress) Finish(snapshotID restic.ID, start time.Time, summary *Summary, dryRun bool) {
+func (b *JSONProgress) Finish(snapshotID restic.ID, summary *archiver.Summary, dryRun bool) {
+	id := ""
+	// empty if snapshot creation was skipped
+	if !snapshotID.IsNull() {
+		id = snapshotID.String()
+	}
+	skipped := snapshotID.IsNull() && !dryRun
 	b.print(summaryOutput{
-		MessageType:         "summary",
-		FilesNew:            summary.Files.New,
-		FilesChanged:        summary.Files.Changed,
-		FilesUnmodified:     summary.Files.Unchanged,
-		DirsNew:             summary.Dirs.New,
-		DirsChanged:         summary.Dirs.Changed,
-		DirsUnmodified:      summary.Dirs.Unchanged,
-		DataBlobs:           summary.ItemStats.DataBlobs,
-		TreeBlobs:           summary.ItemStats.TreeBlobs,
-		DataAdded:           summary.ItemStats.DataSize + summary.ItemStats.TreeSize,
-		TotalFilesProcessed: summary.Files.New + summary.Files.Changed + summary.Files.Unchanged,
-		TotalBytesProcessed: summary.ProcessedBytes,
-		TotalDuration:       time.Since(start).Seconds(),
-		SnapshotID:          snapshotID.String(),
-		DryRun:              dryRun,
+		MessageType:              "summary",
+		FilesNew:                 summary.Files.New,
+		FilesChanged:             summary.Files.Changed,
+		FilesUnmodified:          summary.Files.Unchanged,
+		FilesChangedDuringBackup: summary.FilesChangedDuringBackup,
+		DirsNew:                  summary.Dirs.New,
+		DirsChanged:              summary.Dirs.Changed,
+		DirsUnmodified:           summary.Dirs.Unchanged,
+		DataBlobs:                summary.ItemStats.DataBlobs,
+		TreeBlobs:                summary.ItemStats.TreeBlobs,
+		DataAdded:                summary.ItemStats.DataSize + summary.ItemStats.TreeSize,
+		DataAddedPacked:          summary.ItemStats.DataSizeInRepo + summary.ItemStats.TreeSizeInRepo,
+		TotalFilesProcessed:      summary.Files.New + summary.Files.Changed + summary.Files.Unchanged,
+		TotalBytesProcessed:      summary.ProcessedBytes,
+		TotalDuration:            summary.BackupEnd.Sub(summary.BackupStart).Seconds(),
+		BackupStart:              summary.BackupStart,
+		BackupEnd:                summary.BackupEnd,
+		SnapshotID:               id,
+		SnapshotSkipped:          skipped,
+		DryRun:                   dryRun,
+	})
+}
+
+// ReportHook prints the result of a hook command.
+func (b *JSONProgress) ReportHook(hook, command string, exitCode int, d time.Duration) {
+	b.print(hookOutput{
+		MessageType: "hook",
+		Hook:        hook,
+		Command:     command,
+		ExitCode:    exitCode,
+		Duration:    d.Seconds(),
 	})
 }
 
@@ -219,20 +250,33 @@ type verboseUpdate struct {
 	TotalFiles         uint    `json:"total_files"`
 }
 
+type hookOutput struct {
+	MessageType string  `json:"message_type"` // "hook"
+	Hook        string  `json:"hook"`
+	Command     string  `json:"command"`
+	ExitCode    int     `json:"exit_code"`
+	Duration    float64 `json:"duration"` // in seconds
+}
+
 type summaryOutput struct {
-	MessageType         string  `json:"message_type"` // "summary"
-	FilesNew            uint    `json:"files_new"`
-	FilesChanged        uint    `json:"files_changed"`
-	FilesUnmodified     uint    `json:"files_unmodified"`
-	DirsNew             uint    `json:"dirs_new"`
-	DirsChanged         uint    `json:"dirs_changed"`
-	DirsUnmodified      uint    `json:"dirs_unmodified"`
-	DataBlobs           int     `json:"data_blobs"`
-	TreeBlobs           int     `json:"tree_blobs"`
-	DataAdded           uint64  `json:"data_added"`
-	TotalFilesProcessed uint    `json:"total_files_processed"`
-	TotalBytesProcessed uint64  `json:"total_bytes_processed"`
-	TotalDuration       float64 `json:"total_duration"` // in seconds
-	SnapshotID          string  `json:"snapshot_id"`
-	DryRun              bool    `json:"dry_run,omitempty"`
+	MessageType              string    `json:"message_type"` // "summary"
+	FilesNew                 uint      `json:"files_new"`
+	FilesChanged             uint      `json:"files_changed"`
+	FilesUnmodified          uint      `json:"files_unmodified"`
+	FilesChangedDuringBackup uint      `json:"files_changed_during_backup"`
+	DirsNew                  uint      `json:"dirs_new"`
+	DirsChanged              uint      `json:"dirs_changed"`
+	DirsUnmodified           uint      `json:"dirs_unmodified"`
+	DataBlobs                int       `json:"data_blobs"`
+	TreeBlobs                int       `json:"tree_blobs"`
+	DataAdded                uint64    `json:"data_added"`
+	DataAddedPacked          uint64    `json:"data_added_packed"`
+	TotalFilesProcessed      uint      `json:"total_files_processed"`
+	TotalBytesProcessed      uint64    `json:"total_bytes_processed"`
+	TotalDuration            float64   `json:"total_duration"` // in seconds
+	BackupStart              time.Time `json:"backup_start"`
+	BackupEnd                time.Time `json:"backup_end"`
+	SnapshotID               string    `json:"snapshot_id,omitempty"`
+	SnapshotSkipped          bool      `json:"snapshot_skipped,omitempty"`
+	DryRun                   bool      `json:"dry_run,omitempty"`
 }
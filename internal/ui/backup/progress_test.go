@@ -32,8 +32,10 @@ func (p *mockPrinter) CompleteItem(messageType string, _ string, _ archiver.Item
 	}
 }
 
+func (p *mockPrinter) ChangedDuringBackup(_ string) {}
+
 func (p *mockPrinter) ReportTotal(_ time.Time, _ archiver.ScanStats) {}
-func (p *mockPrinter) Finish(id restic.ID, _ time.Time, summary *Summary, _ bool) {
+func (p *mockPrinter) Finish(id restic.ID, summary *archiver.Summary, _ bool) {
 	p.Lock()
 	defer p.Unlock()
 
@@ -41,6 +43,8 @@ func (p *mockPrinter) Finish(id restic.ID, _ time.Time, summary *Summary, _ bool
 	p.id = id
 }
 
+func (p *mockPrinter) ReportHook(_, _ string, _ int, _ time.Duration) {}
+
 func (p *mockPrinter) Reset() {}
 
 func (p *mockPrinter) P(_ string, _ ...interface{}) {}
@@ -64,7 +68,7 @@ func TestProgress(t *testing.T) {
 
 	time.Sleep(10 * time.Millisecond)
 	id := restic.NewRandomID()
-	prog.Finish(id, false)
+	prog.Finish(id, &archiver.Summary{}, false)
 
 	if !prnt.dirUnchanged {
 		t.Error(`"dir unchanged" event not seen`)
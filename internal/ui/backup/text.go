@@ -110,6 +110,12 @@ func (b *TextProgress) CompleteItem(messageType, item string, s archiver.ItemSta
 	}
 }
 
+// ChangedDuringBackup prints a warning for a file which was modified while
+// reading it.
+func (b *TextProgress) ChangedDuringBackup(item string) {
+	b.E("warning: %v changed during backup, the saved content may be inconsistent\n", termstatus.Quote(item))
+}
+
 // ReportTotal sets the total stats up to now
 func (b *TextProgress) ReportTotal(start time.Time, s archiver.ScanStats) {
 	b.V("scan finished in %.3fs: %v files, %s",
@@ -118,6 +124,11 @@ func (b *TextProgress) ReportTotal(start time.Time, s archiver.ScanStats) {
 	)
 }
 
+// ReportHook prints the result of a hook command in verbose mode.
+func (b *TextProgress) ReportHook(hook, _ string, exitCode int, d time.Duration) {
+	b.V("%v command exited with status %d after %.3fs", hook, exitCode, d.Seconds())
+}
+
 // Reset status
 func (b *TextProgress) Reset() {
 	if b.term.CanUpdateStatus() {
@@ -126,10 +137,13 @@ func (b *TextProgress) Reset() {
 }
 
 // Finish prints the finishing messages.
-func (b *TextProgress) Finish(_ restic.ID, start time.Time, summary *Summary, dryRun bool) {
+func (b *TextProgress) Finish(_ restic.ID, summary *archiver.Summary, dryRun bool) {
 	b.P("\n")
 	b.P("Files:       %5d new, %5d changed, %5d unmodified\n", summary.Files.New, summary.Files.Changed, summary.Files.Unchanged)
 	b.P("Dirs:        %5d new, %5d changed, %5d unmodified\n", summary.Dirs.New, summary.Dirs.Changed, summary.Dirs.Unchanged)
+	if summary.FilesChangedDuringBackup > 0 {
+		b.P("Warning:     %5d files changed during backup\n", summary.FilesChangedDuringBackup)
+	}
 	b.V("Data Blobs:  %5d new\n", summary.ItemStats.DataBlobs)
 	b.V("Tree Blobs:  %5d new\n", summary.ItemStats.TreeBlobs)
 	verb := "Added"
@@ -143,6 +157,6 @@ func (b *TextProgress) Finish(_ restic.ID, start time.Time, summary *Summary, dr
 	b.P("processed %v files, %v in %s",
 		summary.Files.New+summary.Files.Changed+summary.Files.Unchanged,
 		ui.FormatBytes(summary.ProcessedBytes),
-		ui.FormatDuration(time.Since(start)),
+		ui.FormatDuration(summary.BackupEnd.Sub(summary.BackupStart)),
 	)
 }
@@ -7,12 +7,18 @@ import (
 )
 
 type jsonPrinter struct {
-	terminal term
+	terminal  term
+	verbosity uint
+	dryRun    bool
 }
 
-func NewJSONProgress(terminal term) ProgressPrinter {
+// NewJSONProgress returns a printer for the progress of a restore in JSON. If
+// dryRun is set, the summary describes the changes which would have been made.
+func NewJSONProgress(terminal term, verbosity uint, dryRun bool) ProgressPrinter {
 	return &jsonPrinter{
-		terminal: terminal,
+		terminal:  terminal,
+		verbosity: verbosity,
+		dryRun:    dryRun,
 	}
 }
 
@@ -20,31 +26,51 @@ func (t *jsonPrinter) print(status interface{}) {
 	t.terminal.Print(ui.ToJSONString(status))
 }
 
-func (t *jsonPrinter) Update(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
+func (t *jsonPrinter) Update(p State, duration time.Duration) {
 	status := statusUpdate{
 		MessageType:    "status",
 		SecondsElapsed: uint64(duration / time.Second),
-		TotalFiles:     filesTotal,
-		FilesRestored:  filesFinished,
-		TotalBytes:     allBytesTotal,
-		BytesRestored:  allBytesWritten,
+		TotalFiles:     p.FilesTotal,
+		FilesRestored:  p.FilesFinished,
+		TotalBytes:     p.AllBytesTotal,
+		BytesRestored:  p.AllBytesWritten,
 	}
 
-	if allBytesTotal > 0 {
-		status.PercentDone = float64(allBytesWritten) / float64(allBytesTotal)
+	if p.AllBytesTotal > 0 {
+		status.PercentDone = float64(p.AllBytesWritten) / float64(p.AllBytesTotal)
 	}
 
 	t.print(status)
 }
 
-func (t *jsonPrinter) Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
+func (t *jsonPrinter) CompleteItem(action ItemAction, item string, size uint64) {
+	if t.verbosity < 2 {
+		return
+	}
+
+	t.print(verboseUpdate{
+		MessageType: "verbose_status",
+		Action:      string(action),
+		Item:        item,
+		Size:        size,
+	})
+}
+
+func (t *jsonPrinter) Finish(p State, duration time.Duration) {
 	status := summaryOutput{
 		MessageType:    "summary",
 		SecondsElapsed: uint64(duration / time.Second),
-		TotalFiles:     filesTotal,
-		FilesRestored:  filesFinished,
-		TotalBytes:     allBytesTotal,
-		BytesRestored:  allBytesWritten,
+		TotalFiles:     p.FilesTotal,
+		FilesRestored:  p.FilesFinished,
+		TotalBytes:     p.AllBytesTotal,
+		BytesRestored:  p.AllBytesWritten,
+		FilesSkipped:   p.FilesSkipped,
+		BytesSkipped:   p.AllBytesSkipped,
+		FilesDeleted:   p.FilesDeleted,
+		DryRun:         t.dryRun,
+	}
+	if t.dryRun {
+		status.BytesToDownload = &p.BytesToDownload
 	}
 	t.print(status)
 }
@@ -59,6 +85,13 @@ type statusUpdate struct {
 	BytesRestored  uint64  `json:"bytes_restored,omitempty"`
 }
 
+type verboseUpdate struct {
+	MessageType string `json:"message_type"` // "verbose_status"
+	Action      string `json:"action"`
+	Item        string `json:"item"`
+	Size        uint64 `json:"size,omitempty"`
+}
+
 type summaryOutput struct {
 	MessageType    string `json:"message_type"` // "summary"
 	SecondsElapsed uint64 `json:"seconds_elapsed,omitempty"`
@@ -66,4 +99,10 @@ type summaryOutput struct {
 	FilesRestored  uint64 `json:"files_restored,omitempty"`
 	TotalBytes     uint64 `json:"total_bytes,omitempty"`
 	BytesRestored  uint64 `json:"bytes_restored,omitempty"`
+	FilesSkipped   uint64 `json:"files_skipped,omitempty"`
+	BytesSkipped   uint64 `json:"bytes_skipped,omitempty"`
+	FilesDeleted   uint64 `json:"files_deleted,omitempty"`
+	DryRun         bool   `json:"dry_run,omitempty"`
+	// BytesToDownload is only set for a dry run
+	BytesToDownload *uint64 `json:"bytes_to_download,omitempty"`
 }
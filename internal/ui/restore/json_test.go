@@ -9,21 +9,39 @@ import (
 
 func TestJSONPrintUpdate(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewJSONProgress(term)
-	printer.Update(3, 11, 29, 47, 5*time.Second)
+	printer := NewJSONProgress(term, 1, false)
+	printer.Update(State{3, 11, 29, 47, 0, 0, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"{\"message_type\":\"status\",\"seconds_elapsed\":5,\"percent_done\":0.6170212765957447,\"total_files\":11,\"files_restored\":3,\"total_bytes\":47,\"bytes_restored\":29}\n"}, term.output)
 }
 
 func TestJSONPrintSummaryOnSuccess(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewJSONProgress(term)
-	printer.Finish(11, 11, 47, 47, 5*time.Second)
+	printer := NewJSONProgress(term, 1, false)
+	printer.Finish(State{11, 11, 47, 47, 0, 0, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"{\"message_type\":\"summary\",\"seconds_elapsed\":5,\"total_files\":11,\"files_restored\":11,\"total_bytes\":47,\"bytes_restored\":47}\n"}, term.output)
 }
 
 func TestJSONPrintSummaryOnErrors(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewJSONProgress(term)
-	printer.Finish(3, 11, 29, 47, 5*time.Second)
+	printer := NewJSONProgress(term, 1, false)
+	printer.Finish(State{3, 11, 29, 47, 0, 0, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"{\"message_type\":\"summary\",\"seconds_elapsed\":5,\"total_files\":11,\"files_restored\":3,\"total_bytes\":47,\"bytes_restored\":29}\n"}, term.output)
 }
+
+func TestJSONPrintSummaryDryRun(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewJSONProgress(term, 1, true)
+	printer.Finish(State{0, 11, 0, 47, 0, 0, 3, 0}, 5*time.Second)
+	test.Equals(t, []string{"{\"message_type\":\"summary\",\"seconds_elapsed\":5,\"total_files\":11,\"total_bytes\":47,\"files_deleted\":3,\"dry_run\":true,\"bytes_to_download\":0}\n"}, term.output)
+}
+
+func TestJSONPrintCompleteItem(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewJSONProgress(term, 2, false)
+	printer.CompleteItem(ActionDeleted, "/foo", 0)
+	printer.CompleteItem(ActionOverwritten, "/bar", 42)
+	test.Equals(t, []string{
+		"{\"message_type\":\"verbose_status\",\"action\":\"deleted\",\"item\":\"/foo\"}\n",
+		"{\"message_type\":\"verbose_status\",\"action\":\"overwritten\",\"item\":\"/bar\",\"size\":42}\n",
+	}, term.output)
+}
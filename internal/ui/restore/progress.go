@@ -12,15 +12,26 @@ type Progress struct {
 	m       sync.Mutex
 
 	progressInfoMap map[string]progressInfoEntry
-	filesFinished   uint64
-	filesTotal      uint64
-	allBytesWritten uint64
-	allBytesTotal   uint64
+	s               State
 	started         time.Time
 
 	printer ProgressPrinter
 }
 
+// State is the progress of a restore.
+type State struct {
+	FilesFinished   uint64
+	FilesTotal      uint64
+	AllBytesWritten uint64
+	AllBytesTotal   uint64
+	FilesSkipped    uint64
+	AllBytesSkipped uint64
+	FilesDeleted    uint64
+	// BytesToDownload is the amount of data which must be downloaded from
+	// the repository, it is only determined for a dry run.
+	BytesToDownload uint64
+}
+
 type progressInfoEntry struct {
 	bytesWritten uint64
 	bytesTotal   uint64
@@ -31,9 +42,28 @@ type term interface {
 	SetStatus(lines []string)
 }
 
+// ItemAction describes what happened to an item in the target directory.
+type ItemAction string
+
+const (
+	// ActionCreated is reported for items which did not exist before.
+	ActionCreated ItemAction = "created"
+	// ActionOverwritten is reported for existing items which are replaced.
+	ActionOverwritten ItemAction = "overwritten"
+	// ActionMetadataUpdated is reported for existing items whose content
+	// matches the snapshot, only their metadata is restored.
+	ActionMetadataUpdated ItemAction = "metadata_updated"
+	// ActionSkipped is reported for existing items which are kept as is.
+	ActionSkipped ItemAction = "skipped"
+	// ActionDeleted is reported for items which are removed because they do
+	// not exist in the snapshot.
+	ActionDeleted ItemAction = "deleted"
+)
+
 type ProgressPrinter interface {
-	Update(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration)
-	Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration)
+	Update(progress State, duration time.Duration)
+	CompleteItem(action ItemAction, item string, size uint64)
+	Finish(progress State, duration time.Duration)
 }
 
 func NewProgress(printer ProgressPrinter, interval time.Duration) *Progress {
@@ -51,9 +81,9 @@ func (p *Progress) update(runtime time.Duration, final bool) {
 	defer p.m.Unlock()
 
 	if !final {
-		p.printer.Update(p.filesFinished, p.filesTotal, p.allBytesWritten, p.allBytesTotal, runtime)
+		p.printer.Update(p.s, runtime)
 	} else {
-		p.printer.Finish(p.filesFinished, p.filesTotal, p.allBytesWritten, p.allBytesTotal, runtime)
+		p.printer.Finish(p.s, runtime)
 	}
 }
 
@@ -62,8 +92,44 @@ func (p *Progress) AddFile(size uint64) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	p.filesTotal++
-	p.allBytesTotal += size
+	p.s.FilesTotal++
+	p.s.AllBytesTotal += size
+}
+
+// AddSkippedFile records a file with the given size which already exists in
+// the target directory and is not restored again.
+func (p *Progress) AddSkippedFile(size uint64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.s.FilesSkipped++
+	p.s.AllBytesSkipped += size
+}
+
+// AddDeletedFile records an item which was removed from the target directory.
+func (p *Progress) AddDeletedFile(name string) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.s.FilesDeleted++
+	p.printer.CompleteItem(ActionDeleted, name, 0)
+}
+
+// AddDownload records data which must be downloaded for a dry run.
+func (p *Progress) AddDownload(size uint64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.s.BytesToDownload += size
+}
+
+// CompleteItem reports that an item has been restored, or for a dry run that
+// it would be restored, with the given action.
+func (p *Progress) CompleteItem(action ItemAction, name string, size uint64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.printer.CompleteItem(action, name, size)
 }
 
 // AddProgress accumulates the number of bytes written for a file
@@ -78,10 +144,10 @@ func (p *Progress) AddProgress(name string, bytesWrittenPortion uint64, bytesTot
 	entry.bytesWritten += bytesWrittenPortion
 	p.progressInfoMap[name] = entry
 
-	p.allBytesWritten += bytesWrittenPortion
+	p.s.AllBytesWritten += bytesWrittenPortion
 	if entry.bytesWritten == entry.bytesTotal {
 		delete(p.progressInfoMap, name)
-		p.filesFinished++
+		p.s.FilesFinished++
 	}
 }
 
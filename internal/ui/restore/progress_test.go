@@ -22,11 +22,13 @@ type mockPrinter struct {
 
 const mockFinishDuration = 42 * time.Second
 
-func (p *mockPrinter) Update(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
-	p.trace = append(p.trace, printerTraceEntry{filesFinished, filesTotal, allBytesWritten, allBytesTotal, duration, false})
+func (p *mockPrinter) Update(s State, duration time.Duration) {
+	p.trace = append(p.trace, printerTraceEntry{s.FilesFinished, s.FilesTotal, s.AllBytesWritten, s.AllBytesTotal, duration, false})
 }
-func (p *mockPrinter) Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, _ time.Duration) {
-	p.trace = append(p.trace, printerTraceEntry{filesFinished, filesTotal, allBytesWritten, allBytesTotal, mockFinishDuration, true})
+func (p *mockPrinter) CompleteItem(_ ItemAction, _ string, _ uint64) {
+}
+func (p *mockPrinter) Finish(s State, _ time.Duration) {
+	p.trace = append(p.trace, printerTraceEntry{s.FilesFinished, s.FilesTotal, s.AllBytesWritten, s.AllBytesTotal, mockFinishDuration, true})
 }
 
 func testProgress(fn func(progress *Progress) bool) printerTrace {
@@ -5,42 +5,96 @@ import (
 	"time"
 
 	"github.com/restic/restic/internal/ui"
+	"github.com/restic/restic/internal/ui/termstatus"
 )
 
 type textPrinter struct {
-	terminal term
+	terminal  term
+	verbosity uint
+	dryRun    bool
 }
 
-func NewTextProgress(terminal term) ProgressPrinter {
+// NewTextProgress returns a printer for the progress of a restore. If dryRun
+// is set, the summary describes the changes which would have been made.
+func NewTextProgress(terminal term, verbosity uint, dryRun bool) ProgressPrinter {
 	return &textPrinter{
-		terminal: terminal,
+		terminal:  terminal,
+		verbosity: verbosity,
+		dryRun:    dryRun,
 	}
 }
 
-func (t *textPrinter) Update(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
+func (t *textPrinter) Update(p State, duration time.Duration) {
 	timeLeft := ui.FormatDuration(duration)
-	formattedAllBytesWritten := ui.FormatBytes(allBytesWritten)
-	formattedAllBytesTotal := ui.FormatBytes(allBytesTotal)
-	allPercent := ui.FormatPercent(allBytesWritten, allBytesTotal)
+	formattedAllBytesWritten := ui.FormatBytes(p.AllBytesWritten)
+	formattedAllBytesTotal := ui.FormatBytes(p.AllBytesTotal)
+	allPercent := ui.FormatPercent(p.AllBytesWritten, p.AllBytesTotal)
 	progress := fmt.Sprintf("[%s] %s  %v files/dirs %s, total %v files/dirs %v",
-		timeLeft, allPercent, filesFinished, formattedAllBytesWritten, filesTotal, formattedAllBytesTotal)
+		timeLeft, allPercent, p.FilesFinished, formattedAllBytesWritten, p.FilesTotal, formattedAllBytesTotal)
 
 	t.terminal.SetStatus([]string{progress})
 }
 
-func (t *textPrinter) Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
+func (t *textPrinter) CompleteItem(action ItemAction, item string, size uint64) {
+	if t.verbosity < 2 {
+		return
+	}
+
+	var label string
+	switch action {
+	case ActionCreated:
+		label = "created"
+	case ActionOverwritten:
+		label = "overwritten"
+	case ActionMetadataUpdated:
+		label = "metadata"
+	case ActionSkipped:
+		label = "skipped"
+	case ActionDeleted:
+		label = "deleted"
+	default:
+		return
+	}
+
+	line := fmt.Sprintf("%-12s%v", label, termstatus.Quote(item))
+	if size > 0 {
+		line += fmt.Sprintf(" (%v)", ui.FormatBytes(size))
+	}
+	t.terminal.Print(line)
+}
+
+func (t *textPrinter) Finish(p State, duration time.Duration) {
 	t.terminal.SetStatus([]string{})
 
 	timeLeft := ui.FormatDuration(duration)
-	formattedAllBytesTotal := ui.FormatBytes(allBytesTotal)
+	formattedAllBytesTotal := ui.FormatBytes(p.AllBytesTotal)
 
 	var summary string
-	if filesFinished == filesTotal && allBytesWritten == allBytesTotal {
-		summary = fmt.Sprintf("Summary: Restored %d files/dirs (%s) in %s", filesTotal, formattedAllBytesTotal, timeLeft)
+	if t.dryRun {
+		summary = fmt.Sprintf("Summary: Would restore %d files/dirs (%s), download %s",
+			p.FilesTotal, formattedAllBytesTotal, ui.FormatBytes(p.BytesToDownload))
+		if p.FilesSkipped > 0 {
+			summary += fmt.Sprintf(", skip %v files/dirs %v", p.FilesSkipped, ui.FormatBytes(p.AllBytesSkipped))
+		}
+		if p.FilesDeleted > 0 {
+			summary += fmt.Sprintf(", delete %v files/dirs", p.FilesDeleted)
+		}
+		t.terminal.Print(summary)
+		return
+	}
+
+	if p.FilesFinished == p.FilesTotal && p.AllBytesWritten == p.AllBytesTotal {
+		summary = fmt.Sprintf("Summary: Restored %d files/dirs (%s) in %s", p.FilesTotal, formattedAllBytesTotal, timeLeft)
 	} else {
-		formattedAllBytesWritten := ui.FormatBytes(allBytesWritten)
+		formattedAllBytesWritten := ui.FormatBytes(p.AllBytesWritten)
 		summary = fmt.Sprintf("Summary: Restored %d / %d files/dirs (%s / %s) in %s",
-			filesFinished, filesTotal, formattedAllBytesWritten, formattedAllBytesTotal, timeLeft)
+			p.FilesFinished, p.FilesTotal, formattedAllBytesWritten, formattedAllBytesTotal, timeLeft)
+	}
+	if p.FilesSkipped > 0 {
+		summary += fmt.Sprintf(", skipped %v files/dirs %v", p.FilesSkipped, ui.FormatBytes(p.AllBytesSkipped))
+	}
+	if p.FilesDeleted > 0 {
+		summary += fmt.Sprintf(", deleted %v files/dirs", p.FilesDeleted)
 	}
 
 	t.terminal.Print(summary)
@@ -21,21 +21,59 @@ func (m *mockTerm) SetStatus(lines []string) {
 
 func TestPrintUpdate(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewTextProgress(term)
-	printer.Update(3, 11, 29, 47, 5*time.Second)
+	printer := NewTextProgress(term, 1, false)
+	printer.Update(State{3, 11, 29, 47, 0, 0, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"[0:05] 61.70%  3 files/dirs 29 B, total 11 files/dirs 47 B"}, term.output)
 }
 
 func TestPrintSummaryOnSuccess(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewTextProgress(term)
-	printer.Finish(11, 11, 47, 47, 5*time.Second)
+	printer := NewTextProgress(term, 1, false)
+	printer.Finish(State{11, 11, 47, 47, 0, 0, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"Summary: Restored 11 files/dirs (47 B) in 0:05"}, term.output)
 }
 
 func TestPrintSummaryOnErrors(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewTextProgress(term)
-	printer.Finish(3, 11, 29, 47, 5*time.Second)
+	printer := NewTextProgress(term, 1, false)
+	printer.Finish(State{3, 11, 29, 47, 0, 0, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"Summary: Restored 3 / 11 files/dirs (29 B / 47 B) in 0:05"}, term.output)
 }
+
+func TestPrintSummaryWithSkipped(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewTextProgress(term, 1, false)
+	printer.Finish(State{11, 11, 47, 47, 2, 1024, 0, 0}, 5*time.Second)
+	test.Equals(t, []string{"Summary: Restored 11 files/dirs (47 B) in 0:05, skipped 2 files/dirs 1.000 KiB"}, term.output)
+}
+
+func TestPrintSummaryWithDeleted(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewTextProgress(term, 1, false)
+	printer.Finish(State{11, 11, 47, 47, 0, 0, 3, 0}, 5*time.Second)
+	test.Equals(t, []string{"Summary: Restored 11 files/dirs (47 B) in 0:05, deleted 3 files/dirs"}, term.output)
+}
+
+func TestPrintSummaryDryRun(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewTextProgress(term, 1, true)
+	printer.Finish(State{0, 11, 0, 47, 2, 1024, 3, 52}, 5*time.Second)
+	test.Equals(t, []string{"Summary: Would restore 11 files/dirs (47 B), download 52 B, skip 2 files/dirs 1.000 KiB, delete 3 files/dirs"}, term.output)
+}
+
+func TestPrintCompleteItem(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewTextProgress(term, 1, false)
+	printer.CompleteItem(ActionDeleted, "/foo", 0)
+	test.Equals(t, []string(nil), term.output)
+
+	printer = NewTextProgress(term, 2, false)
+	printer.CompleteItem(ActionDeleted, "/foo", 0)
+	printer.CompleteItem(ActionCreated, "/bar", 1024)
+	printer.CompleteItem(ActionMetadataUpdated, "/baz", 0)
+	test.Equals(t, []string{
+		"deleted     /foo",
+		"created     /bar (1.000 KiB)",
+		"metadata    /baz",
+	}, term.output)
+}